@@ -2,11 +2,16 @@ package main
 
 import (
 	"fmt"
+	"log"
+	"os"
+	"time"
 
 	_ "github.com/bayuTri-Code/BE-Recipe/cmd/api/docs"
 	"github.com/bayuTri-Code/BE-Recipe/database"
 	"github.com/bayuTri-Code/BE-Recipe/internal/config"
 	"github.com/bayuTri-Code/BE-Recipe/internal/routes"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -21,8 +26,30 @@ import (
 // @name Authorization
 func main() {
 	config.ConfigDb()
+	if _, err := storage.FromEnv(); err != nil {
+		log.Fatalf("Storage setup failed: %v", err)
+	}
 	db := database.PostgresConn()
 
+	if err := services.NewNutritionService(db).SeedFoods(); err != nil {
+		log.Printf("Failed to seed nutrient dataset: %v", err)
+	}
+
+	stopScheduler := services.StartPublishScheduler(db, envDuration("PUBLISH_SCHEDULER_INTERVAL", time.Minute))
+	defer stopScheduler()
+
+	stopPurger := services.StartTrashPurger(db, envDuration("TRASH_PURGE_INTERVAL", time.Hour))
+	defer stopPurger()
+
+	stopCollector := services.StartOrphanCollector(db, envDuration("ORPHAN_GC_INTERVAL", 24*time.Hour))
+	defer stopCollector()
+
+	stopUploadPurger := services.StartUploadPurger(db, envDuration("UPLOAD_PURGE_INTERVAL", time.Hour))
+	defer stopUploadPurger()
+
+	stopVideoProcessor := services.StartVideoProcessor(db, envDuration("VIDEO_PROCESS_INTERVAL", 30*time.Second))
+	defer stopVideoProcessor()
+
 	r := routes.Routes(db)
 
 	// Swagger
@@ -33,4 +60,13 @@ func main() {
 
 	fmt.Printf("server is running in http://%s:%s\n", host, port)
 	r.Run(host + ":" + port)
-}
\ No newline at end of file
+}
+
+// envDuration reads a positive duration such as "15m" from the environment
+// variable name, or returns fallback when it is unset or invalid.
+func envDuration(name string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bayuTri-Code/BE-Recipe/database"
+	"github.com/bayuTri-Code/BE-Recipe/internal/config"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
+)
+
+// backfill-placeholders computes the BlurHash and dominant color of stored
+// images that were uploaded before placeholders were recorded. Images that
+// already have one are skipped, so it can be run again safely.
+func main() {
+	config.ConfigDb()
+	if _, err := storage.FromEnv(); err != nil {
+		log.Fatalf("Storage setup failed: %v", err)
+	}
+	db := database.PostgresConn()
+
+	report, err := services.NewMediaService(db).BackfillPlaceholders()
+	if err != nil {
+		log.Fatalf("Backfill failed: %v", err)
+	}
+	fmt.Printf("checked %d images, created %d placeholders, %d failed\n", report.Checked, report.Created, report.Failed)
+}
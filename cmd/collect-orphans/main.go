@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/bayuTri-Code/BE-Recipe/database"
+	"github.com/bayuTri-Code/BE-Recipe/internal/config"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
+)
+
+// collect-orphans compares the stored files against the database and lists
+// the ones nothing refers to. Orphans older than -grace are deleted unless
+// -dry-run is set.
+func main() {
+	grace := flag.Duration("grace", services.OrphanGracePeriod(), "only treat files older than this as orphans")
+	dryRun := flag.Bool("dry-run", false, "report orphans without deleting them")
+	flag.Parse()
+
+	config.ConfigDb()
+	if _, err := storage.FromEnv(); err != nil {
+		log.Fatalf("Storage setup failed: %v", err)
+	}
+	db := database.PostgresConn()
+
+	report, err := services.NewMediaService(db).CollectOrphans(*grace, *dryRun)
+	for _, o := range report.Orphans {
+		fmt.Printf("%s\t%d bytes\t%s\n", o.Key, o.Size, o.ModTime.Format("2006-01-02 15:04:05"))
+	}
+	if err != nil {
+		log.Fatalf("Collection failed: %v", err)
+	}
+	fmt.Printf("scanned %d files, found %d orphans, deleted %d\n", report.Scanned, len(report.Orphans), report.Deleted)
+}
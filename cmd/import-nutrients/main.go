@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/bayuTri-Code/BE-Recipe/database"
+	"github.com/bayuTri-Code/BE-Recipe/internal/config"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+)
+
+// import-nutrients loads a nutrient CSV into the foods table. Without -file
+// the dataset bundled in database/seed is imported. With -recalculate every
+// recipe's cached nutrition and dietary labels are rebuilt afterwards.
+func main() {
+	file := flag.String("file", "", "path to a nutrient CSV (defaults to the bundled dataset)")
+	recalculate := flag.Bool("recalculate", false, "recalculate nutrition for every recipe after importing")
+	flag.Parse()
+
+	config.ConfigDb()
+	db := database.PostgresConn()
+	service := services.NewNutritionService(db)
+
+	var src io.Reader = bytes.NewReader(database.NutrientsCSV)
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("Cannot open %s: %v", *file, err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	n, err := service.ImportFoods(src)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	fmt.Printf("imported %d foods\n", n)
+
+	if !*recalculate {
+		return
+	}
+
+	var recipes []models.Recipe
+	if err := db.Select("id").Find(&recipes).Error; err != nil {
+		log.Fatalf("Cannot list recipes: %v", err)
+	}
+	for _, r := range recipes {
+		if err := service.RecalculateRecipe(db, r.ID); err != nil {
+			log.Printf("recipe %s: %v", r.ID, err)
+			continue
+		}
+		if err := services.RecalculateLabels(db, r.ID); err != nil {
+			log.Printf("recipe %s: %v", r.ID, err)
+		}
+	}
+	fmt.Printf("recalculated %d recipes\n", len(recipes))
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"log"
+	"strings"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"gorm.io/gorm"
+)
+
+// migrateLegacyCategories moves the free-text recipes.category values into
+// the categories table. Values that slugify the same ("Food", "food ") end up
+// in one category. The old value is cleared as the recipe is linked, so a
+// category an author removes later is not brought back on the next start.
+func migrateLegacyCategories(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Recipe{}, "category") {
+		return nil
+	}
+
+	var names []string
+	if err := db.Table("recipes").
+		Distinct("category").
+		Where("category_id IS NULL AND category IS NOT NULL AND TRIM(category) <> ''").
+		Pluck("category", &names).Error; err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		slug := utils.Slugify(name)
+		if slug == "" {
+			continue
+		}
+
+		category := models.Category{Slug: slug}
+		if err := db.Where(models.Category{Slug: slug}).
+			Attrs(models.Category{Name: strings.TrimSpace(name)}).
+			FirstOrCreate(&category).Error; err != nil {
+			return err
+		}
+
+		if err := db.Table("recipes").
+			Where("category_id IS NULL AND category = ?", name).
+			Updates(map[string]interface{}{"category_id": category.ID, "category": nil}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(names) > 0 {
+		log.Printf("Migrated %d legacy category values", len(names))
+	}
+	return nil
+}
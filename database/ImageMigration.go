@@ -0,0 +1,70 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"gorm.io/gorm"
+)
+
+// migrateLegacyThumbnails adds the thumbnail of every recipe that has no
+// images yet to its gallery as the cover image, so the gallery is the only
+// place images live. Running it again does nothing.
+func migrateLegacyThumbnails(db *gorm.DB) error {
+	var recipes []models.Recipe
+	if err := db.Unscoped().
+		Select("id, thumbnail").
+		Where("thumbnail <> '' AND NOT EXISTS (SELECT 1 FROM recipe_images WHERE recipe_images.recipe_id = recipes.id)").
+		Find(&recipes).Error; err != nil {
+		return err
+	}
+
+	for _, r := range recipes {
+		image := models.RecipeImage{RecipeID: r.ID, Key: r.Thumbnail, Position: 1, IsCover: true}
+		if err := db.Create(&image).Error; err != nil {
+			return err
+		}
+	}
+	if len(recipes) > 0 {
+		log.Printf("Moved %d recipe thumbnails into galleries", len(recipes))
+	}
+	return nil
+}
+
+// legacyImagePaths maps the URL paths images used to be linked under to the
+// storage key prefix that replaced them.
+var legacyImagePaths = map[string]string{
+	"/storage/":         "storage/",
+	"/profile-storage/": "profile_storage/",
+	"/profile-banner/":  "profile_banner/",
+}
+
+// migrateImageKeys turns the image URLs stored before storage was pluggable
+// into storage keys, such as "http://host/storage/a.jpg" into
+// "storage/a.jpg". Running it again does nothing.
+func migrateImageKeys(db *gorm.DB) error {
+	columns := []struct{ table, column string }{
+		{"recipes", "thumbnail"},
+		{"users", "avatar"},
+		{"users", "banner"},
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, c := range columns {
+			for urlPath, prefix := range legacyImagePaths {
+				res := tx.Exec(
+					fmt.Sprintf(`UPDATE %q SET %q = ? || split_part(%q, ?, 2) WHERE %q LIKE ?`, c.table, c.column, c.column, c.column),
+					prefix, urlPath, "%://%"+urlPath+"%",
+				)
+				if res.Error != nil {
+					return res.Error
+				}
+				if res.RowsAffected > 0 {
+					log.Printf("Turned %d %s.%s URLs into storage keys", res.RowsAffected, c.table, c.column)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -46,6 +46,18 @@ func autoMigrate(db *gorm.DB) {
 		&models.Ingredient{},
 		&models.Step{},
 		&models.Favorite{},
+		&models.Food{},
+		&models.Tag{},
+		&models.Category{},
+		&models.CategoryTranslation{},
+		&models.RecipeShare{},
+		&models.RecipeRevision{},
+		&models.RecipeImage{},
+		&models.RecipeVideo{},
+		&models.StoredFile{},
+		&models.Upload{},
+		&models.CookingSession{},
+		&models.CookingTimer{},
 		&dto.BlacklistedToken{},
 	)
 
@@ -53,4 +65,14 @@ func autoMigrate(db *gorm.DB) {
 		log.Fatalf("Auto Migration Failed: %v", err)
 	}
 	log.Println("Auto Migration Complete!")
+
+	if err := migrateLegacyCategories(db); err != nil {
+		log.Fatalf("Category Migration Failed: %v", err)
+	}
+	if err := migrateImageKeys(db); err != nil {
+		log.Fatalf("Image Migration Failed: %v", err)
+	}
+	if err := migrateLegacyThumbnails(db); err != nil {
+		log.Fatalf("Image Migration Failed: %v", err)
+	}
 }
\ No newline at end of file
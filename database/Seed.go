@@ -0,0 +1,9 @@
+package database
+
+import _ "embed"
+
+// NutrientsCSV is the bundled nutrient dataset (values per 100 g), imported
+// into the foods table on first start and by cmd/import-nutrients.
+//
+//go:embed seed/nutrients.csv
+var NutrientsCSV []byte
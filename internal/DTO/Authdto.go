@@ -18,10 +18,11 @@ type LoginRequest struct {
 }
 
 type UpdateProfileRequest struct {
-	Name  string `json:"name" binding:"omitempty"`
-	Email string `json:"email" binding:"omitempty,email"`
-	Bio   string `json:"bio" binding:"omitempty"`
-	Avatar string `json:"avatar" binding:"omitempty"`
+	Name       string `json:"name" binding:"omitempty"`
+	Email      string `json:"email" binding:"omitempty,email"`
+	Bio        string `json:"bio" binding:"omitempty"`
+	Avatar     string `json:"avatar" binding:"omitempty"`
+	UnitSystem string `json:"unit_system" binding:"omitempty,oneof=metric us"`
 }
 
 type BaseResponse struct {
@@ -41,19 +42,26 @@ type LoginResponse struct {
 }
 
 type UserResponse struct {
-	UserId string `json:"user_id"`
-	Name   string `json:"name"`
-	Email  string `json:"email"`
-	Bio	string `json:"bio"`
+	UserId     string `json:"user_id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Bio        string `json:"bio"`
+	UnitSystem string `json:"unit_system"`
 }
 
 type UpdateProfileResponse struct {
-	UserId string `json:"user_id"`
-	Name   string `json:"name"`
-	Email  string `json:"email"`
-	Bio    string `json:"bio"`
-	Avatar string `json:"avatar" binding:"omitempty"`
-	Banner string `json:"banner" binding:"omitempty"`
+	UserId     string `json:"user_id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Bio        string `json:"bio"`
+	Avatar     string `json:"avatar" binding:"omitempty"`
+	Banner     string `json:"banner" binding:"omitempty"`
+	UnitSystem string `json:"unit_system"`
+
+	AvatarVariants    ImageVariants     `json:"avatar_variants,omitempty"`
+	BannerVariants    ImageVariants     `json:"banner_variants,omitempty"`
+	AvatarPlaceholder *ImagePlaceholder `json:"avatar_placeholder,omitempty"`
+	BannerPlaceholder *ImagePlaceholder `json:"banner_placeholder,omitempty"`
 }
 
 type EmailRequest struct {
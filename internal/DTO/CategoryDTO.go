@@ -0,0 +1,22 @@
+package dto
+
+import "github.com/google/uuid"
+
+type CategoryRequest struct {
+	Name         string     `json:"name" binding:"required"`
+	Slug         string     `json:"slug"`
+	ParentID     *uuid.UUID `json:"parent_id"`
+	DisplayOrder int        `json:"display_order"`
+	// Translations maps a locale ("id", "en-US") to the localized name.
+	Translations map[string]string `json:"translations"`
+}
+
+type CategoryResponse struct {
+	ID           uuid.UUID          `json:"id"`
+	ParentID     *uuid.UUID         `json:"parent_id"`
+	Name         string             `json:"name"`
+	Slug         string             `json:"slug"`
+	DisplayOrder int                `json:"display_order"`
+	Translations map[string]string  `json:"translations,omitempty"`
+	Children     []CategoryResponse `json:"children"`
+}
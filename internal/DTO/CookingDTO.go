@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TimerStatusRunning   = "running"
+	TimerStatusDone      = "done"
+	TimerStatusCancelled = "cancelled"
+)
+
+type CookingStepRequest struct {
+	CurrentStep int `json:"current_step" binding:"required"`
+}
+
+// StartTimerRequest starts a timer. With a step_id and no duration the
+// step's own duration is used.
+type StartTimerRequest struct {
+	StepID          *uuid.UUID `json:"step_id"`
+	Label           string     `json:"label"`
+	DurationSeconds int        `json:"duration_seconds"`
+}
+
+type CookingTimerResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	StepID           *uuid.UUID `json:"step_id,omitempty"`
+	Label            string     `json:"label"`
+	DurationSeconds  int        `json:"duration_seconds"`
+	StartedAt        time.Time  `json:"started_at"`
+	EndsAt           time.Time  `json:"ends_at"`
+	RemainingSeconds int        `json:"remaining_seconds"`
+	Status           string     `json:"status"`
+}
+
+type CookingSessionResponse struct {
+	ID          uuid.UUID              `json:"id"`
+	RecipeID    uuid.UUID              `json:"recipe_id"`
+	RecipeTitle string                 `json:"recipe_title"`
+	CurrentStep int                    `json:"current_step"`
+	TotalSteps  int                    `json:"total_steps"`
+	Step        *StepResponse          `json:"step,omitempty"`
+	Timers      []CookingTimerResponse `json:"timers"`
+	StartedAt   time.Time              `json:"started_at"`
+	FinishedAt  *time.Time             `json:"finished_at,omitempty"`
+}
+
+// StreamTokenResponse carries a token for opening a cooking session's event
+// stream with ?token=, for clients that cannot send headers.
+type StreamTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
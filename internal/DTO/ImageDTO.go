@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImageVariants maps an image format ("webp", "jpg") to a srcset listing
+// every stored size, ready for <img srcset> or <source srcset>. It is empty
+// for images uploaded before variants were generated.
+type ImageVariants map[string]string
+
+// ImagePlaceholder is shown while an image loads: a BlurHash
+// (https://blurha.sh) and the image's dominant color as "#rrggbb". It is
+// left out for images that have not been analysed yet.
+type ImagePlaceholder struct {
+	BlurHash string `json:"blurhash"`
+	Color    string `json:"color"`
+}
+
+type RecipeImageResponse struct {
+	ID       uuid.UUID     `json:"id"`
+	StepID   *uuid.UUID    `json:"step_id,omitempty"`
+	URL      string        `json:"url"`
+	Variants ImageVariants `json:"variants,omitempty"`
+	AltText  string        `json:"alt_text"`
+	Position int           `json:"position"`
+	IsCover  bool          `json:"is_cover"`
+
+	Placeholder *ImagePlaceholder `json:"placeholder,omitempty"`
+}
+
+// UpdateImageRequest changes the fields that are set. A step_id attaches the
+// image to that step; an empty step_id moves it back to the gallery.
+type UpdateImageRequest struct {
+	AltText *string `json:"alt_text"`
+	StepID  *string `json:"step_id"`
+}
+
+// UploadResponse describes a resumable upload. URL and Variants are set once
+// the upload is complete.
+type UploadResponse struct {
+	ID          uuid.UUID     `json:"id"`
+	Kind        string        `json:"kind"`
+	Filename    string        `json:"filename"`
+	ContentType string        `json:"content_type"`
+	Length      int64         `json:"length"`
+	Offset      int64         `json:"offset"`
+	Complete    bool          `json:"complete"`
+	URL         string        `json:"url,omitempty"`
+	Variants    ImageVariants `json:"variants,omitempty"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+
+	Placeholder *ImagePlaceholder `json:"placeholder,omitempty"`
+}
@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // ---- Requests ----
 type CreateRecipeRequest struct {
@@ -10,9 +14,15 @@ type CreateRecipeRequest struct {
 	PrepTime    int    `json:"prep_time"`
 	CookTime    int    `json:"cook_time"`
 	Servings    int    `json:"servings"`
+	// Status defaults to published; scheduled requires PublishAt.
+	Status     string     `json:"status"`
+	PublishAt  *time.Time `json:"publish_at"`
+	Visibility string     `json:"visibility"`
 
 	Ingredients []IngredientInput `json:"ingredients"`
 	Steps       []StepInput       `json:"steps"`
+	Tags        []string          `json:"tags"`
+	Videos      []VideoInput      `json:"videos"`
 }
 
 type UpdateRecipeRequest struct {
@@ -22,19 +32,53 @@ type UpdateRecipeRequest struct {
 	PrepTime    *int    `json:"prep_time"`
 	CookTime    *int    `json:"cook_time"`
 	Servings    *int    `json:"servings"`
+	Status      *string    `json:"status"`
+	PublishAt   *time.Time `json:"publish_at"`
+	Visibility  *string    `json:"visibility"`
+
+	Ingredients []IngredientInput `json:"ingredients"`
+	Steps       []StepInput       `json:"steps"`
+	Tags        []string          `json:"tags"`
+}
 
+// RecipeDocument is the writable representation of a recipe that PATCH
+// merge patches are applied to.
+type RecipeDocument struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Category    string            `json:"category"`
+	PrepTime    int               `json:"prep_time"`
+	CookTime    int               `json:"cook_time"`
+	Servings    int               `json:"servings"`
+	Status      string            `json:"status"`
+	PublishAt   *time.Time        `json:"publish_at"`
+	Visibility  string            `json:"visibility"`
+	Tags        []string          `json:"tags"`
 	Ingredients []IngredientInput `json:"ingredients"`
 	Steps       []StepInput       `json:"steps"`
 }
 
+// IngredientInput and StepInput may carry the ID of an existing row so an
+// edit keeps it; rows without an ID are matched by name or step number.
 type IngredientInput struct {
-	Name   string `json:"name" binding:"required"`
-	Amount string `json:"amount" binding:"required"`
+	ID       *uuid.UUID `json:"id,omitempty"`
+	Name     string   `json:"name" binding:"required"`
+	Amount   string   `json:"amount" binding:"required"`
+	Quantity *float64   `json:"quantity"`
+	Unit     string     `json:"unit"`
+	FoodID   *uuid.UUID `json:"food_id"`
+	Group    string     `json:"group,omitempty"`
 }
 
 type StepInput struct {
-	Number int    `json:"number" binding:"required"`
-	Detail string `json:"detail" binding:"required"`
+	ID      *uuid.UUID `json:"id,omitempty"`
+	Number  int        `json:"number" binding:"required"`
+	Detail  string     `json:"detail" binding:"required"`
+	Section string     `json:"section,omitempty"`
+
+	DurationSeconds *int     `json:"duration_seconds,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TemperatureUnit string   `json:"temperature_unit,omitempty"`
 }
 
 type UserSummaryResponse struct {
@@ -42,18 +86,38 @@ type UserSummaryResponse struct {
 	Name   string    `json:"name"`
 	Email  string    `json:"email"`
 	Avatar string    `json:"avatar"`
+
+	AvatarVariants    ImageVariants     `json:"avatar_variants,omitempty"`
+	AvatarPlaceholder *ImagePlaceholder `json:"avatar_placeholder,omitempty"`
 }
 
 type IngredientResponse struct {
-	ID     uuid.UUID `json:"id"`
-	Name   string    `json:"name"`
-	Amount string    `json:"amount"`
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Amount   string    `json:"amount"`
+	Quantity   *float64   `json:"quantity,omitempty"`
+	Unit       string     `json:"unit,omitempty"`
+	FoodID     *uuid.UUID `json:"food_id,omitempty"`
+	FoodManual bool       `json:"food_manual"`
+	Position   int        `json:"position"`
+	Group      string     `json:"group,omitempty"`
 }
 
 type StepResponse struct {
-	ID     uuid.UUID `json:"id"`
-	Number int       `json:"number"`
-	Detail string    `json:"detail"`
+	ID      uuid.UUID `json:"id"`
+	Number  int       `json:"number"`
+	Detail  string    `json:"detail"`
+	Section string    `json:"section,omitempty"`
+
+	// The *Detected flags mark values read from the step text rather than
+	// set by the author.
+	DurationSeconds     *int     `json:"duration_seconds,omitempty"`
+	DurationDetected    bool     `json:"duration_detected,omitempty"`
+	Temperature         *float64 `json:"temperature,omitempty"`
+	TemperatureUnit     string   `json:"temperature_unit,omitempty"`
+	TemperatureDetected bool     `json:"temperature_detected,omitempty"`
+
+	Images []RecipeImageResponse `json:"images,omitempty"`
 }
 
 type FavoriteResponse struct {
@@ -61,19 +125,124 @@ type FavoriteResponse struct {
 	UserID uuid.UUID `json:"user_id"`
 }
 
+// FavoriteRecipeResponse is one of the user's favorites with its recipe.
+type FavoriteRecipeResponse struct {
+	ID       uuid.UUID      `json:"id"`
+	UserID   uuid.UUID      `json:"user_id"`
+	RecipeID uuid.UUID      `json:"recipe_id"`
+	Recipe   RecipeResponse `json:"recipe"`
+}
+
 type RecipeResponse struct {
 	ID          uuid.UUID             `json:"id"`
 	Title       string                `json:"title"`
 	Description string                `json:"description"`
 	Category    string                `json:"category"`
+	CategoryID  *uuid.UUID            `json:"category_id"`
+	CategorySlug string               `json:"category_slug"`
 	Thumbnail   string                `json:"thumbnail"`
+	ThumbnailVariants ImageVariants   `json:"thumbnail_variants,omitempty"`
+	ThumbnailPlaceholder *ImagePlaceholder `json:"thumbnail_placeholder,omitempty"`
+	// Images is the gallery; the cover is also returned as Thumbnail.
+	Images      []RecipeImageResponse `json:"images"`
+	Videos      []RecipeVideoResponse `json:"videos"`
 	User        UserSummaryResponse `json:"user"`
 	Ingredients []IngredientResponse  `json:"ingredients"`
 	Steps       []StepResponse        `json:"steps"`
+
+	// IngredientGroups and StepSections list the group and section names in
+	// order, with "" for items outside any group. They are left out when the
+	// recipe does not use groups or sections.
+	IngredientGroups []string `json:"ingredient_groups,omitempty"`
+	StepSections     []string `json:"step_sections,omitempty"`
+
 	PrepTime    int                   `json:"prep_time"`
 	CookTime    int                   `json:"cook_time"`
 	Servings    int                   `json:"servings"`
 	Favorites   []FavoriteResponse    `json:"favorites"`
+	Nutrition   *NutritionResponse    `json:"nutrition,omitempty"`
+
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Visibility  string     `json:"visibility"`
+
+	// ForkedFrom is set on forks: "adapted from <Title> by <AuthorName>".
+	// It is empty when the viewer cannot see the original.
+	ForkedFrom *ForkAttribution `json:"forked_from,omitempty"`
+	ForkCount  int              `json:"fork_count"`
+	Version    int              `json:"version"`
+
+	Allergens      []string        `json:"allergens"`
+	DietTags       []string        `json:"diet_tags"`
+	LabelOverrides map[string]bool `json:"label_overrides,omitempty"`
+	Tags           []TagResponse   `json:"tags"`
+}
+
+type ForkAttribution struct {
+	RecipeID   *uuid.UUID `json:"recipe_id,omitempty"`
+	Title      string     `json:"title,omitempty"`
+	AuthorID   *uuid.UUID `json:"author_id,omitempty"`
+	AuthorName string     `json:"author_name,omitempty"`
+}
+
+// TrashedRecipeResponse is a deleted recipe waiting in the trash.
+type TrashedRecipeResponse struct {
+	Recipe    RecipeResponse `json:"recipe"`
+	DeletedAt time.Time      `json:"deleted_at"`
+	PurgeAt   time.Time      `json:"purge_at"`
+}
+
+// MyRecipesResponse lists the caller's recipes in every status together with
+// the number of recipes per status.
+type MyRecipesResponse struct {
+	Recipes []RecipeResponse `json:"recipes"`
+	Counts  map[string]int64 `json:"counts"`
+}
+
+type LabelOverridesRequest struct {
+	Overrides map[string]bool `json:"overrides"`
+}
+
+// RecipeFilter narrows recipe listings by dietary labels, tags and category.
+type RecipeFilter struct {
+	// Category is a category slug or ID; its descendants are included.
+	Category         string
+	Diet             []string
+	Allergens        []string
+	ExcludeAllergens []string
+	Tags             []string
+	// TagMode is "all" (recipe has every tag) or "any" (at least one).
+	TagMode string
+}
+
+type NutritionResponse struct {
+	Calories      float64    `json:"calories"`
+	ProteinG      float64    `json:"protein_g"`
+	FatG          float64    `json:"fat_g"`
+	CarbohydrateG float64    `json:"carbohydrate_g"`
+	FiberG        float64    `json:"fiber_g"`
+	SugarsG       float64    `json:"sugars_g"`
+	SodiumMg      float64    `json:"sodium_mg"`
+	CalciumMg     float64    `json:"calcium_mg"`
+	IronMg        float64    `json:"iron_mg"`
+	PotassiumMg   float64    `json:"potassium_mg"`
+	VitaminCMg    float64    `json:"vitamin_c_mg"`
+	PerServing    bool       `json:"per_serving"`
+	Complete      bool       `json:"complete"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
+
+type FoodResponse struct {
+	ID          uuid.UUID `json:"id"`
+	SourceID    int       `json:"source_id"`
+	Description string    `json:"description"`
+	Aliases     []string  `json:"aliases"`
+	PieceGrams  float64   `json:"piece_grams,omitempty"`
+}
+
+type IngredientFoodRequest struct {
+	FoodID *uuid.UUID `json:"food_id"`
 }
 
 type AddFavoriteRequest struct {
@@ -0,0 +1,54 @@
+package dto
+
+import "github.com/google/uuid"
+
+// IngredientRequest adds a single ingredient. Position is 1-based; without
+// it the ingredient is appended. Afterwards ingredients of the same group
+// are pulled together, with groups in the order they first appear.
+type IngredientRequest struct {
+	Name     string     `json:"name" binding:"required"`
+	Amount   string     `json:"amount" binding:"required"`
+	Quantity *float64   `json:"quantity"`
+	Unit     string     `json:"unit"`
+	FoodID   *uuid.UUID `json:"food_id"`
+	Group    string     `json:"group"`
+	Position *int       `json:"position"`
+}
+
+type UpdateIngredientRequest struct {
+	Name     *string  `json:"name"`
+	Amount   *string  `json:"amount"`
+	Quantity *float64 `json:"quantity"`
+	Unit     *string  `json:"unit"`
+	Group    *string  `json:"group"`
+	Position *int     `json:"position"`
+}
+
+// StepRequest adds a single step. Without a number the step is appended;
+// with one it is inserted there and the following steps move down.
+type StepRequest struct {
+	Number          *int     `json:"number"`
+	Detail          string   `json:"detail" binding:"required"`
+	Section         string   `json:"section"`
+	DurationSeconds *int     `json:"duration_seconds"`
+	Temperature     *float64 `json:"temperature"`
+	TemperatureUnit string   `json:"temperature_unit"`
+}
+
+// UpdateStepRequest changes the fields that are set. A duration_seconds or
+// temperature of 0 removes the author's value so it is read from the text
+// again.
+type UpdateStepRequest struct {
+	Number          *int     `json:"number"`
+	Detail          *string  `json:"detail"`
+	Section         *string  `json:"section"`
+	DurationSeconds *int     `json:"duration_seconds"`
+	Temperature     *float64 `json:"temperature"`
+	TemperatureUnit *string  `json:"temperature_unit"`
+}
+
+// ReorderRequest lists every ingredient or step ID of a recipe in the new
+// order.
+type ReorderRequest struct {
+	IDs []uuid.UUID `json:"ids" binding:"required"`
+}
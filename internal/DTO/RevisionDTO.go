@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecipeSnapshot is the content of a recipe stored in a revision. Lifecycle
+// fields (status, visibility) and the thumbnail are not part of it.
+type RecipeSnapshot struct {
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	CategoryID  *uuid.UUID           `json:"category_id"`
+	PrepTime    int                  `json:"prep_time"`
+	CookTime    int                  `json:"cook_time"`
+	Servings    int                  `json:"servings"`
+	Tags        []string             `json:"tags"`
+	Ingredients []SnapshotIngredient `json:"ingredients"`
+	Steps       []StepInput          `json:"steps"`
+}
+
+type SnapshotIngredient struct {
+	Name       string     `json:"name"`
+	Amount     string     `json:"amount"`
+	Quantity   *float64   `json:"quantity,omitempty"`
+	Unit       string     `json:"unit,omitempty"`
+	FoodID     *uuid.UUID `json:"food_id,omitempty"`
+	FoodManual bool       `json:"food_manual,omitempty"`
+	Group      string     `json:"group,omitempty"`
+}
+
+type RevisionResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	Number    int             `json:"number"`
+	UserID    uuid.UUID       `json:"user_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Snapshot  *RecipeSnapshot `json:"snapshot,omitempty"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type IngredientChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type StepChange struct {
+	Number int    `json:"number"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// RevisionDiff describes what changed between two revisions. Ingredients are
+// matched by name and steps by number.
+type RevisionDiff struct {
+	From   int           `json:"from"`
+	To     int           `json:"to"`
+	Fields []FieldChange `json:"fields"`
+
+	IngredientsAdded   []SnapshotIngredient `json:"ingredients_added"`
+	IngredientsRemoved []SnapshotIngredient `json:"ingredients_removed"`
+	IngredientsChanged []IngredientChange   `json:"ingredients_changed"`
+
+	StepsAdded   []StepInput  `json:"steps_added"`
+	StepsRemoved []StepInput  `json:"steps_removed"`
+	StepsChanged []StepChange `json:"steps_changed"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateShareRequest struct {
+	// ExpiresAt is optional; links without it stay valid until revoked.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ShareResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	RecipeID  uuid.UUID  `json:"recipe_id"`
+	Token     string     `json:"token"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+}
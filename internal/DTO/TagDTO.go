@@ -0,0 +1,24 @@
+package dto
+
+import "github.com/google/uuid"
+
+type TagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type MergeTagRequest struct {
+	IntoID uuid.UUID `json:"into_id" binding:"required"`
+}
+
+type TagResponse struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+}
+
+type TagCountResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	RecipeCount int64     `json:"recipe_count"`
+}
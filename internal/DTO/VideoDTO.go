@@ -0,0 +1,32 @@
+package dto
+
+import "github.com/google/uuid"
+
+// VideoInput attaches a video: the ID of a finished video upload, or the URL
+// of a video on an allowed site.
+type VideoInput struct {
+	UploadID string `json:"upload_id"`
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+}
+
+// RecipeVideoResponse describes a recipe video. URL is the file of an
+// uploaded video or the link to an external one. Duration, size and poster
+// are set once Status is ready.
+type RecipeVideoResponse struct {
+	ID       uuid.UUID `json:"id"`
+	Source   string    `json:"source"`
+	URL      string    `json:"url"`
+	Provider string    `json:"provider,omitempty"`
+	Title    string    `json:"title"`
+	Position int       `json:"position"`
+	Status   string    `json:"status"`
+
+	DurationSeconds float64       `json:"duration_seconds,omitempty"`
+	Width           int           `json:"width,omitempty"`
+	Height          int           `json:"height,omitempty"`
+	PosterURL       string        `json:"poster_url,omitempty"`
+	PosterVariants  ImageVariants `json:"poster_variants,omitempty"`
+
+	PosterPlaceholder *ImagePlaceholder `json:"poster_placeholder,omitempty"`
+}
@@ -85,10 +85,11 @@ func LoginHandler(c *gin.Context) {
 		},
 		Token: token,
 		Data: dto.UserResponse{
-			UserId: user.ID.String(),
-			Name:   user.Name,
-			Email:  user.Email,
-			Bio:    user.Bio,
+			UserId:     user.ID.String(),
+			Name:       user.Name,
+			Email:      user.Email,
+			Bio:        user.Bio,
+			UnitSystem: user.UnitSystem,
 		},
 	})
 }
@@ -157,6 +158,7 @@ func LogoutHandler(c *gin.Context) {
 // @Param name formData string false "Name of the user"
 // @Param email formData string false "Email address of the user"
 // @Param bio formData string false "Short biography"
+// @Param unit_system formData string false "Preferred measurement system (metric or us)"
 // @Param avatar formData file false "Avatar image file"
 // @Param banner formData file false "banner image file"
 // @Success 200 {object} dto.UpdateProfileResponse "Successfully updated profile"
@@ -181,11 +183,12 @@ func UpdateProfileHandler(c *gin.Context) {
 	name := c.PostForm("name")
 	email := c.PostForm("email")
 	bio := c.PostForm("bio")
+	unitSystem := c.PostForm("unit_system")
 
 	avatarFile, _ := c.FormFile("avatar")
 	bannerFile, _ := c.FormFile("banner") 
 
-	res, err := services.UpdateProfile(userID, name, email, bio, avatarFile, bannerFile)
+	res, err := services.UpdateProfile(userID, name, email, bio, unitSystem, avatarFile, bannerFile)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
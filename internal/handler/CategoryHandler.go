@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	Service *services.CategoryService
+}
+
+func NewCategoryHandler(s *services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{Service: s}
+}
+
+func categoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCategoryExists), errors.Is(err, services.ErrCategoryInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// ListCategories godoc
+// @Summary List the category tree
+// @Description List categories as a tree ordered by display order, with names in the requested locale
+// @Tags Categories
+// @Produce json
+// @Param locale query string false "Locale for category names (defaults to Accept-Language)"
+// @Success 200 {array} dto.CategoryResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/categories [get]
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	locale := c.Query("locale")
+	if locale == "" {
+		locale = c.GetHeader("Accept-Language")
+	}
+
+	categories, err := h.Service.ListCategories(locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch categories"})
+		return
+	}
+	c.JSON(http.StatusOK, categories)
+}
+
+// CreateCategory godoc
+// @Summary Create a category (admin)
+// @Tags Categories
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.CategoryRequest true "Category"
+// @Success 201 {object} dto.CategoryResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/categories [post]
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req dto.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	category, err := h.Service.CreateCategory(req)
+	if err != nil {
+		categoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Update or move a category (admin)
+// @Description Replace the name, slug, parent, display order and translations of a category
+// @Tags Categories
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param request body dto.CategoryRequest true "Category"
+// @Success 200 {object} dto.CategoryResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/categories/{id} [put]
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	var req dto.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	category, err := h.Service.UpdateCategory(c.Param("id"), req)
+	if err != nil {
+		categoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category (admin)
+// @Description Delete a category. Recipes and subcategories are moved to move_to when given; otherwise a category in use cannot be deleted.
+// @Tags Categories
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param move_to query string false "Category ID that receives the recipes and subcategories"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/categories/{id} [delete]
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	if err := h.Service.DeleteCategory(c.Param("id"), c.Query("move_to")); err != nil {
+		categoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "category deleted"})
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// cookingHeartbeat is how often an idle event stream is pinged and reloaded,
+// which also picks up changes made through other server instances.
+const cookingHeartbeat = 15 * time.Second
+
+type CookingHandler struct {
+	Service *services.CookingService
+}
+
+func NewCookingHandler(s *services.CookingService) *CookingHandler {
+	return &CookingHandler{Service: s}
+}
+
+func cookingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound),
+		errors.Is(err, services.ErrRecipeNotFound),
+		errors.Is(err, services.ErrStepNotFound),
+		errors.Is(err, services.ErrTimerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSessionFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// StartSession godoc
+// @Summary Start cooking a recipe
+// @Description Start a cooking session for a recipe, or return the one you already have running for it
+// @Tags Cooking
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} dto.CookingSessionResponse
+// @Success 201 {object} dto.CookingSessionResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/cook [post]
+func (h *CookingHandler) StartSession(c *gin.Context) {
+	res, created, err := h.Service.StartSession(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		cookingError(c, err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, res)
+}
+
+// ListSessions godoc
+// @Summary List running cooking sessions
+// @Tags Cooking
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.CookingSessionResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/cooking-sessions [get]
+func (h *CookingHandler) ListSessions(c *gin.Context) {
+	list, err := h.Service.ListSessions(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch cooking sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetSession godoc
+// @Summary Get a cooking session
+// @Description Get the current step and the timers of a cooking session
+// @Tags Cooking
+// @Security BearerAuth
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} dto.CookingSessionResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/cooking-sessions/{session_id} [get]
+func (h *CookingHandler) GetSession(c *gin.Context) {
+	res, err := h.Service.GetSession(c.GetString("userID"), c.Param("session_id"))
+	if err != nil {
+		cookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// SetStep godoc
+// @Summary Move to another step
+// @Tags Cooking
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Param request body dto.CookingStepRequest true "Step number"
+// @Success 200 {object} dto.CookingSessionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/cooking-sessions/{session_id}/step [put]
+func (h *CookingHandler) SetStep(c *gin.Context) {
+	var req dto.CookingStepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.SetStep(c.GetString("userID"), c.Param("session_id"), req.CurrentStep)
+	if err != nil {
+		cookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// FinishSession godoc
+// @Summary Finish cooking
+// @Description End a cooking session and cancel its running timers
+// @Tags Cooking
+// @Security BearerAuth
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} dto.CookingSessionResponse
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/cooking-sessions/{session_id} [delete]
+func (h *CookingHandler) FinishSession(c *gin.Context) {
+	res, err := h.Service.FinishSession(c.GetString("userID"), c.Param("session_id"))
+	if err != nil {
+		cookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// StartTimer godoc
+// @Summary Start a timer
+// @Description Start a countdown. With a step_id and no duration_seconds the step's duration is used.
+// @Tags Cooking
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Param request body dto.StartTimerRequest true "Timer"
+// @Success 201 {object} dto.CookingTimerResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/cooking-sessions/{session_id}/timers [post]
+func (h *CookingHandler) StartTimer(c *gin.Context) {
+	var req dto.StartTimerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.StartTimer(c.GetString("userID"), c.Param("session_id"), req)
+	if err != nil {
+		cookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+// CancelTimer godoc
+// @Summary Cancel a timer
+// @Tags Cooking
+// @Security BearerAuth
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Param timer_id path string true "Timer ID"
+// @Success 200 {object} dto.CookingTimerResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/cooking-sessions/{session_id}/timers/{timer_id} [delete]
+func (h *CookingHandler) CancelTimer(c *gin.Context) {
+	res, err := h.Service.CancelTimer(c.GetString("userID"), c.Param("session_id"), c.Param("timer_id"))
+	if err != nil {
+		cookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// StreamToken godoc
+// @Summary Get a token for the cooking session event stream
+// @Description Returns a token that opens the session's event stream as /events?token=... for a minute, for clients such as EventSource that cannot send an Authorization header. An open stream is not cut off when the token expires; reconnecting needs a new token.
+// @Tags Cooking
+// @Security BearerAuth
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} dto.StreamTokenResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/cooking-sessions/{session_id}/events/token [post]
+func (h *CookingHandler) StreamToken(c *gin.Context) {
+	userID := c.GetString("userID")
+	session, err := h.Service.GetSession(userID, c.Param("session_id"))
+	if err != nil {
+		cookingError(c, err)
+		return
+	}
+	expires := time.Now().Add(utils.StreamTokenTTL)
+	c.JSON(http.StatusOK, dto.StreamTokenResponse{
+		Token:     utils.SignStreamToken(userID, session.ID.String(), expires),
+		ExpiresAt: expires,
+	})
+}
+
+// SessionEvents godoc
+// @Summary Stream cooking session events
+// @Description Server-sent events for a cooking session. A "session" event carries the full state on connect and after every change; a "timer" event is sent when a timer expires. The stream ends after the session is finished. Clients that cannot send an Authorization header pass a token from /events/token as ?token= instead.
+// @Tags Cooking
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param session_id path string true "Session ID"
+// @Param token query string false "Stream token, instead of the Authorization header"
+// @Success 200 {object} dto.CookingSessionResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/cooking-sessions/{session_id}/events [get]
+func (h *CookingHandler) SessionEvents(c *gin.Context) {
+	userID, sessionID := c.GetString("userID"), c.Param("session_id")
+	session, err := h.Service.GetSession(userID, sessionID)
+	if err != nil {
+		cookingError(c, err)
+		return
+	}
+
+	changes, stop := h.Service.Watch(session.ID)
+	defer stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Timers that already expired are part of the first state event.
+	announced := map[uuid.UUID]bool{}
+	for _, t := range session.Timers {
+		if t.Status != dto.TimerStatusRunning {
+			announced[t.ID] = true
+		}
+	}
+	c.SSEvent("session", session)
+	c.Writer.Flush()
+
+	for session.FinishedAt == nil {
+		wait := cookingHeartbeat
+		for _, t := range session.Timers {
+			if left := time.Until(t.EndsAt); t.Status == dto.TimerStatusRunning && left < wait {
+				wait = max(left, 0)
+			}
+		}
+		timer := time.NewTimer(wait)
+
+		changed := false
+		select {
+		case <-c.Request.Context().Done():
+			timer.Stop()
+			return
+		case <-changes:
+			timer.Stop()
+			changed = true
+		case <-timer.C:
+		}
+
+		next, err := h.Service.GetSession(userID, sessionID)
+		if err != nil {
+			return
+		}
+		expired := false
+		for _, t := range next.Timers {
+			if t.Status == dto.TimerStatusDone && !announced[t.ID] {
+				c.SSEvent("timer", t)
+				expired = true
+			}
+			if t.Status != dto.TimerStatusRunning {
+				announced[t.ID] = true
+			}
+		}
+		session = next
+
+		switch {
+		case changed || session.FinishedAt != nil:
+			c.SSEvent("session", session)
+		case !expired:
+			_, _ = c.Writer.WriteString(": ping\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
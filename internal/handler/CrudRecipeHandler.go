@@ -2,13 +2,16 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/bayuTri-Code/BE-Recipe/database"
 	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
-	"github.com/bayuTri-Code/BE-Recipe/internal/models"
 	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -21,6 +24,213 @@ func NewRecipeHandler(s *services.RecipeService) *RecipeHandler {
 	return &RecipeHandler{Service: s}
 }
 
+// unitSystem resolves the measurement system a response is rendered in: an
+// explicit ?units= query wins over the caller's saved preference.
+func (h *RecipeHandler) unitSystem(c *gin.Context) string {
+	if units := c.Query("units"); utils.IsValidUnitSystem(units) {
+		return units
+	}
+	if userID := c.GetString("userID"); userID != "" {
+		return h.Service.UnitSystemFor(userID)
+	}
+	return ""
+}
+
+func splitQuery(c *gin.Context, key string) []string {
+	var out []string
+	for _, v := range strings.Split(c.Query(key), ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseTagList accepts tags either as a JSON array or comma-separated.
+func parseTagList(value string) ([]string, error) {
+	tags := []string{}
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &tags); err != nil {
+			return nil, err
+		}
+		return tags, nil
+	}
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags, nil
+}
+
+func parseRecipeFilter(c *gin.Context) (dto.RecipeFilter, error) {
+	filter := dto.RecipeFilter{
+		Diet:             splitQuery(c, "diet"),
+		Allergens:        splitQuery(c, "allergens"),
+		ExcludeAllergens: splitQuery(c, "exclude_allergens"),
+		Category:         c.Query("category"),
+		Tags:             splitQuery(c, "tags"),
+		TagMode:          strings.ToLower(c.DefaultQuery("tag_mode", "all")),
+	}
+	if filter.TagMode != "all" && filter.TagMode != "any" {
+		return filter, errors.New("tag_mode must be all or any")
+	}
+	for _, labels := range [][]string{filter.Diet, filter.Allergens, filter.ExcludeAllergens} {
+		if err := services.ValidateLabels(labels); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+func (h *RecipeHandler) localizeAll(c *gin.Context, list []dto.RecipeResponse) []dto.RecipeResponse {
+	system := h.unitSystem(c)
+	if system == "" {
+		return list
+	}
+	for i := range list {
+		list[i] = h.Service.LocalizeRecipe(list[i], system)
+	}
+	return list
+}
+
+// parseCreateForm reads a recipe from a multipart form where ingredients,
+// steps and tags are JSON-encoded fields.
+func parseCreateForm(c *gin.Context) (dto.CreateRecipeRequest, error) {
+	var req dto.CreateRecipeRequest
+	req.Title = c.PostForm("title")
+	req.Description = c.PostForm("description")
+	req.Category = c.PostForm("category")
+
+	if prepTime := c.PostForm("prep_time"); prepTime != "" {
+		if val, err := strconv.Atoi(prepTime); err == nil {
+			req.PrepTime = val
+		}
+	}
+	if cookTime := c.PostForm("cook_time"); cookTime != "" {
+		if val, err := strconv.Atoi(cookTime); err == nil {
+			req.CookTime = val
+		}
+	}
+	if servings := c.PostForm("servings"); servings != "" {
+		if val, err := strconv.Atoi(servings); err == nil {
+			req.Servings = val
+		}
+	}
+
+	if ingredientsJSON := c.PostForm("ingredients"); ingredientsJSON != "" {
+		if err := json.Unmarshal([]byte(ingredientsJSON), &req.Ingredients); err != nil {
+			return req, errors.New("invalid ingredients format: " + err.Error())
+		}
+	}
+
+	if stepsJSON := c.PostForm("steps"); stepsJSON != "" {
+		if err := json.Unmarshal([]byte(stepsJSON), &req.Steps); err != nil {
+			return req, errors.New("invalid steps format: " + err.Error())
+		}
+	}
+
+	if videosJSON := c.PostForm("videos"); videosJSON != "" {
+		if err := json.Unmarshal([]byte(videosJSON), &req.Videos); err != nil {
+			return req, errors.New("invalid videos format: " + err.Error())
+		}
+	}
+
+	if tagsValue := c.PostForm("tags"); tagsValue != "" {
+		tags, err := parseTagList(tagsValue)
+		if err != nil {
+			return req, errors.New("invalid tags format: " + err.Error())
+		}
+		req.Tags = tags
+	}
+
+	req.Status = strings.ToLower(c.PostForm("status"))
+	req.Visibility = strings.ToLower(c.PostForm("visibility"))
+	if publishAt := c.PostForm("publish_at"); publishAt != "" {
+		t, err := time.Parse(time.RFC3339, publishAt)
+		if err != nil {
+			return req, errors.New("publish_at must be an RFC 3339 timestamp")
+		}
+		req.PublishAt = &t
+	}
+	return req, nil
+}
+
+// parseUpdateForm reads recipe changes from a multipart form. Fields that
+// are not sent stay unchanged; an empty description, category or tags value
+// clears it.
+func parseUpdateForm(c *gin.Context) (dto.UpdateRecipeRequest, error) {
+	var req dto.UpdateRecipeRequest
+
+	if title := c.PostForm("title"); title != "" {
+		req.Title = &title
+	}
+	if description, ok := c.GetPostForm("description"); ok {
+		req.Description = &description
+	}
+	if category, ok := c.GetPostForm("category"); ok {
+		req.Category = &category
+	}
+
+	if prepTime := c.PostForm("prep_time"); prepTime != "" {
+		if val, err := strconv.Atoi(prepTime); err == nil {
+			req.PrepTime = &val
+		}
+	}
+	if cookTime := c.PostForm("cook_time"); cookTime != "" {
+		if val, err := strconv.Atoi(cookTime); err == nil {
+			req.CookTime = &val
+		}
+	}
+	if servings := c.PostForm("servings"); servings != "" {
+		if val, err := strconv.Atoi(servings); err == nil {
+			req.Servings = &val
+		}
+	}
+
+	if ingredientsJSON := c.PostForm("ingredients"); ingredientsJSON != "" {
+		var ingredients []dto.IngredientInput
+		if err := json.Unmarshal([]byte(ingredientsJSON), &ingredients); err != nil {
+			return req, errors.New("invalid ingredients format: " + err.Error())
+		}
+		req.Ingredients = ingredients
+	}
+
+	if stepsJSON := c.PostForm("steps"); stepsJSON != "" {
+		var steps []dto.StepInput
+		if err := json.Unmarshal([]byte(stepsJSON), &steps); err != nil {
+			return req, errors.New("invalid steps format: " + err.Error())
+		}
+		req.Steps = steps
+	}
+
+	if status := c.PostForm("status"); status != "" {
+		status = strings.ToLower(status)
+		req.Status = &status
+	}
+	if publishAt := c.PostForm("publish_at"); publishAt != "" {
+		t, err := time.Parse(time.RFC3339, publishAt)
+		if err != nil {
+			return req, errors.New("publish_at must be an RFC 3339 timestamp")
+		}
+		req.PublishAt = &t
+	}
+	if visibility := c.PostForm("visibility"); visibility != "" {
+		visibility = strings.ToLower(visibility)
+		req.Visibility = &visibility
+	}
+
+	if tagsValue, ok := c.GetPostForm("tags"); ok {
+		tags, err := parseTagList(tagsValue)
+		if err != nil {
+			return req, errors.New("invalid tags format: " + err.Error())
+		}
+		req.Tags = tags
+	}
+	return req, nil
+}
+
 // CreateRecipe godoc
 // @Summary Create a new recipe by the authenticated user
 // @Description Create a new recipe with title, description, category, prep_time, cook_time, ingredients, steps, and thumbnail
@@ -32,17 +242,23 @@ func NewRecipeHandler(s *services.RecipeService) *RecipeHandler {
 // @Failure 400 {object} map[string]string
 // @Router /api/recipes [post]
 // CreateRecipe godoc
+// @Description Accepts a multipart form or a JSON dto.CreateRecipeRequest.
 // @Tags Recipes
-// @Accept multipart/form-data
+// @Accept multipart/form-data,json
 // @Produce json
 // @Param title formData string true "Recipe Title"
 // @Param description formData string false "Recipe Description"
-// @Param category formData string false "Recipe Category"
+// @Param category formData string false "Category slug, ID or name"
 // @Param prep_time formData int false "Preparation Time"
 // @Param cook_time formData int false "Cooking Time"
 // @Param servings formData int false "Number of Servings"
 // @Param ingredients formData string false "Ingredients JSON Array"
 // @Param steps formData string false "Steps JSON Array"
+// @Param tags formData string false "Tags as a JSON array or comma-separated"
+// @Param status formData string false "draft, scheduled, published (default) or archived"
+// @Param publish_at formData string false "RFC 3339 time to publish a scheduled recipe"
+// @Param visibility formData string false "private, unlisted or public (default)"
+// @Param videos formData string false "Videos JSON Array of {upload_id or url, title}"
 // @Success 201 {object} dto.RecipeResponse
 // @Failure 400 {object} map[string]string
 // @Router /api/recipes [post]
@@ -55,54 +271,35 @@ func (h *RecipeHandler) CreateRecipe(c *gin.Context) {
 	}
 
 	var req dto.CreateRecipeRequest
-	req.Title = c.PostForm("title")
-	req.Description = c.PostForm("description")
-	req.Category = c.PostForm("category")
-
-	if prepTime := c.PostForm("prep_time"); prepTime != "" {
-		if val, err := strconv.Atoi(prepTime); err == nil {
-			req.PrepTime = val
-		}
-	}
-	if cookTime := c.PostForm("cook_time"); cookTime != "" {
-		if val, err := strconv.Atoi(cookTime); err == nil {
-			req.CookTime = val
-		}
-	}
-	if servings := c.PostForm("servings"); servings != "" {
-		if val, err := strconv.Atoi(servings); err == nil {
-			req.Servings = val
-		}
-	}
-
-	if ingredientsJSON := c.PostForm("ingredients"); ingredientsJSON != "" {
-		if err := json.Unmarshal([]byte(ingredientsJSON), &req.Ingredients); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ingredients format: " + err.Error()})
+	var thumbnail *multipart.FileHeader
+	if c.ContentType() == "application/json" {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
 			return
 		}
-	}
-
-	if stepsJSON := c.PostForm("steps"); stepsJSON != "" {
-		if err := json.Unmarshal([]byte(stepsJSON), &req.Steps); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid steps format: " + err.Error()})
+		req.Status = strings.ToLower(req.Status)
+		req.Visibility = strings.ToLower(req.Visibility)
+	} else {
+		req, err = parseCreateForm(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		thumbnail, _ = c.FormFile("thumbnail")
 	}
 
-	if req.Title == "" {
+	if strings.TrimSpace(req.Title) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
 		return
 	}
 
-	thumbnail, _ := c.FormFile("thumbnail")
-
 	res, err := h.Service.CreateRecipe(req, uid, thumbnail)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, res)
+	writeRecipe(c, http.StatusCreated, res)
 }
 
 // GetRecipes godoc
@@ -110,40 +307,89 @@ func (h *RecipeHandler) CreateRecipe(c *gin.Context) {
 // @Description Retrieve all available recipes
 // @Tags Recipes
 // @Produce json
+// @Param units query string false "Render amounts in this measurement system (metric or us)"
+// @Param diet query string false "Comma-separated diet tags the recipe must have (vegan, vegetarian, halal-friendly, gluten-free)"
+// @Param allergens query string false "Comma-separated allergens the recipe must contain"
+// @Param exclude_allergens query string false "Comma-separated allergens the recipe must not contain (gluten, dairy, nuts, shellfish, egg, soy)"
+// @Param tags query string false "Comma-separated tag slugs"
+// @Param tag_mode query string false "all (default) requires every tag, any requires at least one"
+// @Param category query string false "Category slug or ID, subcategories included"
 // @Success 200 {array} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
 // @Router /api/recipes [get]
 func (h *RecipeHandler) GetAllRecipes(c *gin.Context) {
-	list, err := h.Service.GetAllRecipes()
+	filter, err := parseRecipeFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.Service.GetAllRecipes(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch recipes"})
 		return
 	}
-	c.JSON(http.StatusOK, list)
+	c.JSON(http.StatusOK, h.localizeAll(c, list))
 }
 
 // GetRecipeByID godoc
 // @Summary Get recipe by ID
-// @Description Retrieve a recipe by its ID
+// @Description Retrieve a recipe by its ID. Login is optional; private, unlisted and unpublished recipes are only returned to their author.
 // @Tags Recipes
+// @Security BearerAuth
 // @Produce json
 // @Param id path string true "Recipe ID"
+// @Param servings query int false "Scale ingredient amounts to this number of servings"
+// @Param units query string false "Render amounts in this measurement system (metric or us), defaults to the caller's preference"
+// @Param If-None-Match header string false "ETag from an earlier response; 304 is returned when the same rendering is unchanged"
 // @Success 200 {object} dto.RecipeResponse
+// @Success 304 "Not Modified"
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /api/recipes/{id} [get]
 func (h *RecipeHandler) GetRecipeByID(c *gin.Context) {
 	id := c.Param("id")
-	res, err := h.Service.GetRecipeByID(id)
+	res, err := h.Service.GetRecipeByID(id, c.GetString("userID"))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "recipe not found"})
 		return
 	}
-	c.JSON(http.StatusOK, res)
+
+	if servingsStr := c.Query("servings"); servingsStr != "" {
+		servings, err := strconv.Atoi(servingsStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid servings"})
+			return
+		}
+		res, err = h.Service.ScaleRecipe(res, servings)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	res = h.Service.LocalizeRecipe(res, h.unitSystem(c))
+
+	// The tag is taken from the finished body, so a different rendering of
+	// the same version is never answered with 304.
+	body, etag, err := renderRecipe(res)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render recipe"})
+		return
+	}
+	c.Header("ETag", etag)
+	c.Header("Vary", "Authorization")
+	if inm := c.GetHeader("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
 }
 
 
 // GetByCategory godoc
 // @Summary Get recipes by category
-// @Description Get all recipes filtered by category (case-insensitive). If no category is provided, returns all recipes.
+// @Description Get all recipes in a category and its subcategories. The category can be given as slug, ID or (localized) name. If no category is provided, returns all recipes.
 // @Tags Recipes
 // @Accept json
 // @Produce json
@@ -151,30 +397,28 @@ func (h *RecipeHandler) GetRecipeByID(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Success"
 // @Failure 500 {object} map[string]interface{} "Internal Server Error"
 // @Router /recipesByCategory [get]
-func GetRecipesByCategory(c *gin.Context) {
-    category := c.Query("category") 
-
-    var recipes []models.Recipe
-    if category != "" {
-        database.Db.Where("LOWER(category) = LOWER(?)", category).Find(&recipes)
-    } else {
-        database.Db.Find(&recipes)
-    }
-
-    c.JSON(http.StatusOK, gin.H{
-        "recipes": recipes,
-    })
-}
-
+func (h *RecipeHandler) GetRecipesByCategory(c *gin.Context) {
+	recipes, err := h.Service.GetAllRecipes(dto.RecipeFilter{Category: c.Query("category")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch recipes"})
+		return
+	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"recipes": h.localizeAll(c, recipes),
+	})
+}
 
 // GetMyRecipes godoc
 // @Summary Get my recipes 
-// @Description Get all recipes created by the authenticated user
+// @Description Get all recipes created by the authenticated user in every status, with the number of recipes per status
 // @Tags Recipes
 // @Security BearerAuth
 // @Produce json
-// @Success 200 {array} dto.RecipeResponse
+// @Param units query string false "Render amounts in this measurement system (metric or us)"
+// @Param status query string false "Only list recipes in this status (draft, scheduled, published, archived)"
+// @Success 200 {object} dto.MyRecipesResponse
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Router /api/myrecipes [get]
 func (h *RecipeHandler) GetMyRecipes(c *gin.Context) {
@@ -184,13 +428,24 @@ func (h *RecipeHandler) GetMyRecipes(c *gin.Context) {
 		return
 	}
 
-	recipes, err := h.Service.GetRecipesByUserID(userID.(string))
+	status := strings.ToLower(c.Query("status"))
+	if status != "" && !services.IsValidRecipeStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft, scheduled, published or archived"})
+		return
+	}
+
+	recipes, err := h.Service.GetRecipesByUserID(userID.(string), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
+		return
+	}
+	counts, err := h.Service.StatusCounts(userID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"recipes": recipes})
+	c.JSON(http.StatusOK, dto.MyRecipesResponse{Recipes: h.localizeAll(c, recipes), Counts: counts})
 }
 
 // UpdateRecipe godoc
@@ -206,98 +461,210 @@ func (h *RecipeHandler) GetMyRecipes(c *gin.Context) {
 // @Router /api/recipes/{id} [put]
 // UpdateRecipe godoc
 // @Summary Update recipe
+// @Description Accepts a multipart form or a JSON dto.UpdateRecipeRequest. Fields left out are kept; an empty value clears description, category and tags.
 // @Tags Recipes
-// @Accept multipart/form-data
+// @Accept multipart/form-data,json
 // @Produce json
 // @Param id path string true "Recipe ID"
 // @Param title formData string false "Recipe Title"
 // @Param description formData string false "Recipe Description"
-// @Param category formData string false "Recipe Category"
+// @Param category formData string false "Category slug, ID or name; send an empty value to remove the category"
 // @Param prep_time formData int false "Preparation Time"
 // @Param cook_time formData int false "Cooking Time"
 // @Param servings formData int false "Number of Servings"
 // @Param ingredients formData string false "Ingredients JSON Array"
 // @Param steps formData string false "Steps JSON Array"
+// @Param tags formData string false "Tags as a JSON array or comma-separated; send an empty value to remove all tags"
+// @Param status formData string false "draft, scheduled, published or archived"
+// @Param publish_at formData string false "RFC 3339 time to publish a scheduled recipe"
+// @Param visibility formData string false "private, unlisted or public"
+// @Param If-Match header string true "ETag of the recipe version the changes are based on, or *"
 // @Success 200 {object} dto.RecipeResponse
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Router /api/recipes/{id} [put]
 func (h *RecipeHandler) UpdateRecipe(c *gin.Context) {
 	id := c.Param("id")
-
-	var req dto.UpdateRecipeRequest
-
-	if title := c.PostForm("title"); title != "" {
-		req.Title = &title
-	}
-	if description := c.PostForm("description"); description != "" {
-		req.Description = &description
-	}
-	if category := c.PostForm("category"); category != "" {
-		req.Category = &category
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
 	}
 
-	if prepTime := c.PostForm("prep_time"); prepTime != "" {
-		if val, err := strconv.Atoi(prepTime); err == nil {
-			req.PrepTime = &val
+	var req dto.UpdateRecipeRequest
+	var thumbnail *multipart.FileHeader
+	if c.ContentType() == "application/json" {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+			return
 		}
-	}
-	if cookTime := c.PostForm("cook_time"); cookTime != "" {
-		if val, err := strconv.Atoi(cookTime); err == nil {
-			req.CookTime = &val
+	} else {
+		var err error
+		req, err = parseUpdateForm(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		thumbnail, _ = c.FormFile("thumbnail")
 	}
-	if servings := c.PostForm("servings"); servings != "" {
-		if val, err := strconv.Atoi(servings); err == nil {
-			req.Servings = &val
+
+	h.saveRecipe(c, id, version, req, thumbnail)
+}
+
+// saveRecipe applies an update and writes the response shared by PUT and
+// PATCH.
+func (h *RecipeHandler) saveRecipe(c *gin.Context, id string, version *int, req dto.UpdateRecipeRequest, thumbnail *multipart.FileHeader) {
+	res, err := h.Service.UpdateRecipe(c.GetString("userID"), id, version, req, thumbnail)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRecipeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrVersionMismatch):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		}
+		return
 	}
 
-	if ingredientsJSON := c.PostForm("ingredients"); ingredientsJSON != "" {
-		var ingredients []dto.IngredientInput
-		if err := json.Unmarshal([]byte(ingredientsJSON), &ingredients); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ingredients format: " + err.Error()})
-			return
-		}
-		req.Ingredients = ingredients
+	writeRecipe(c, http.StatusOK, res)
+}
+
+// PatchRecipe godoc
+// @Summary Partially update recipe
+// @Description Apply an RFC 7396 JSON merge patch to a recipe. Fields left out are kept, null removes a value (title, status and visibility cannot be removed), and tags, ingredients and steps are replaced as a whole. Use the thumbnail endpoint to change the image.
+// @Tags Recipes
+// @Security BearerAuth
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string true "ETag of the recipe version the changes are based on, or *"
+// @Param request body dto.RecipeDocument true "Merge patch"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 428 {object} map[string]string
+// @Router /api/recipes/{id} [patch]
+func (h *RecipeHandler) PatchRecipe(c *gin.Context) {
+	id := c.Param("id")
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+	if ct := c.ContentType(); ct != "application/merge-patch+json" && ct != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/merge-patch+json"})
+		return
 	}
 
-	if stepsJSON := c.PostForm("steps"); stepsJSON != "" {
-		var steps []dto.StepInput
-		if err := json.Unmarshal([]byte(stepsJSON), &steps); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid steps format: " + err.Error()})
-			return
-		}
-		req.Steps = steps
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
 	}
 
-	thumbnail, _ := c.FormFile("thumbnail")
+	current, err := h.Service.GetRecipeByID(id, c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrRecipeNotFound.Error()})
+		return
+	}
+	// The patch is applied to the version read here, so with If-Match: * the
+	// update still fails rather than overwriting a concurrent change.
+	if version == nil {
+		version = &current.Version
+	}
 
-	res, err := h.Service.UpdateRecipe(id, req, thumbnail)
+	req, err := services.ApplyMergePatch(services.RecipeDocumentFrom(current), body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	
-	c.JSON(http.StatusOK, res)
+	if req.Status != nil {
+		*req.Status = strings.ToLower(*req.Status)
+	}
+	if req.Visibility != nil {
+		*req.Visibility = strings.ToLower(*req.Visibility)
+	}
+
+	h.saveRecipe(c, id, version, req, nil)
 }
 
 // DeleteRecipe godoc
 // @Summary Delete recipe
-// @Description Delete a recipe by ID
+// @Description Move a recipe to the trash. It can be restored until the retention period ends.
 // @Tags Recipes
+// @Security BearerAuth
 // @Produce json
 // @Param id path string true "Recipe ID"
+// @Param If-Match header string true "ETag of the recipe version being deleted, or *"
 // @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Router /api/recipes/{id} [delete]
 func (h *RecipeHandler) DeleteRecipe(c *gin.Context) {
 	id := c.Param("id")
-	if err := services.DeleteRecipeService(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete recipe"})
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteRecipe(c.GetString("userID"), id, version); err != nil {
+		switch {
+		case errors.Is(err, services.ErrRecipeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrVersionMismatch):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete recipe"})
+		}
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "recipe deleted"})
 }
 
+// SetLabelOverrides godoc
+// @Summary Override allergen and diet labels
+// @Description Replace the author's overrides of the labels derived from ingredients. true forces a label on, false removes it; an empty object clears all overrides.
+// @Tags Recipes
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param request body dto.LabelOverridesRequest true "Label overrides"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/labels [put]
+func (h *RecipeHandler) SetLabelOverrides(c *gin.Context) {
+	var req dto.LabelOverridesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.SetLabelOverrides(c.GetString("userID"), c.Param("id"), req.Overrides)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRecipeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
 
+	c.JSON(http.StatusOK, res)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/bayuTri-Code/BE-Recipe/internal/services"
@@ -22,14 +23,14 @@ func NewFavoriteHandler(s *services.FavoriteService) *FavoriteHandler {
 // @Tags         Favorites
 // @Produce      json
 // @Security     BearerAuth
-// @Param        recipe_id   path      string  true  "Recipe ID"
+// @Param        id          path      string  true  "Recipe ID"
 // @Success      200  {object}  map[string]string "message: 'Added to favorites' or 'Removed from favorites'"
 // @Failure      400  {object}  map[string]string "Invalid recipe ID"
 // @Failure      401  {object}  map[string]string "Unauthorized"
 // @Failure      500  {object}  map[string]string "Internal server error"
-// @Router       /api/recipes/{recipe_id}/favorites [post]
+// @Router       /api/recipes/{id}/favorites [post]
 func (h *FavoriteHandler) AddFavoriteHandler(c *gin.Context) {
-	recipeID := c.Param("recipe_id")
+	recipeID := c.Param("id")
 	if recipeID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "recipe_id is required"})
 		return
@@ -42,6 +43,10 @@ func (h *FavoriteHandler) AddFavoriteHandler(c *gin.Context) {
 	}
 
 	added, err := h.Service.AddFavoriteService(userID.(string), recipeID)
+	if errors.Is(err, services.ErrRecipeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -101,7 +106,7 @@ func (h *FavoriteHandler) AddFavoriteHandler(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {array}   models.Favorite
+// @Success      200  {array}   dto.FavoriteRecipeResponse
 // @Failure      401  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /api/recipes/favorites [get]
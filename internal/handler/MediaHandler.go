@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type MediaHandler struct {
+	Service *services.MediaService
+}
+
+func NewMediaHandler(s *services.MediaService) *MediaHandler {
+	return &MediaHandler{Service: s}
+}
+
+// Serve godoc
+// @Summary Fetch a stored file
+// @Description Images of published public recipes and profile pictures can be fetched by key. Images of other recipes need the signed, expiring link the API returns for them.
+// @Tags Media
+// @Produce octet-stream
+// @Param key path string true "Object key"
+// @Param expires query int false "Unix time the signed link expires"
+// @Param signature query string false "Signature of the link"
+// @Success 200 {file} binary
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /media/{key} [get]
+func (h *MediaHandler) Serve(c *gin.Context) {
+	h.serve(c, strings.TrimPrefix(c.Param("key"), "/"))
+}
+
+// Legacy serves the paths files were published under before storage was
+// pluggable, so links handed out back then keep working.
+func (h *MediaHandler) Legacy(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.serve(c, prefix+c.Param("key"))
+	}
+}
+
+func (h *MediaHandler) serve(c *gin.Context, key string) {
+	signature := c.Query("signature")
+	f, err := h.Service.Open(key, c.Query("expires"), signature)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMediaForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMediaNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
+		}
+		return
+	}
+	defer f.Close()
+
+	// Signed links must not end up in shared caches.
+	if signature != "" {
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(utils.MediaURLTTL().Seconds())))
+	} else {
+		c.Header("Cache-Control", "public, max-age=86400")
+	}
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, path.Base(key), time.Time{}, rs)
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", contentType)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	_, _ = io.Copy(c.Writer, f)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type NutritionHandler struct {
+	Service *services.NutritionService
+}
+
+func NewNutritionHandler(s *services.NutritionService) *NutritionHandler {
+	return &NutritionHandler{Service: s}
+}
+
+// SearchFoods godoc
+// @Summary Search the nutrient dataset
+// @Description Search foods in the bundled nutrient dataset by description or alias, used to pick a manual ingredient mapping
+// @Tags Nutrition
+// @Produce json
+// @Param q query string false "Search text (example: bawang putih)"
+// @Success 200 {array} dto.FoodResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/foods [get]
+func (h *NutritionHandler) SearchFoods(c *gin.Context) {
+	foods, err := h.Service.SearchFoods(c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search foods"})
+		return
+	}
+	c.JSON(http.StatusOK, foods)
+}
+
+// SetIngredientFood godoc
+// @Summary Override the food an ingredient is mapped to
+// @Description Map an ingredient to a specific food in the nutrient dataset. Send a null food_id to go back to automatic matching. Nutrition is recalculated.
+// @Tags Nutrition
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param ingredient_id path string true "Ingredient ID"
+// @Param request body dto.IngredientFoodRequest true "Food mapping"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/ingredients/{ingredient_id}/food [put]
+func (h *NutritionHandler) SetIngredientFood(c *gin.Context) {
+	var req dto.IngredientFoodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.SetIngredientFood(c.GetString("userID"), c.Param("id"), c.Param("ingredient_id"), req.FoodID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRecipeNotFound), errors.Is(err, services.ErrIngredientNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
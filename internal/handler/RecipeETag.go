@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/gin-gonic/gin"
+)
+
+// recipeBodyETag tags one rendering of a recipe: its version, which writes
+// accept in If-Match, and a hash of the body, which changes with the
+// servings, the unit system and the expiry of signed media links.
+func recipeBodyETag(version int, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + strconv.Itoa(version) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// renderRecipe marshals a recipe response and tags the body, so every
+// response carrying a recipe has an ETag of the same form.
+func renderRecipe(res dto.RecipeResponse) (body []byte, etag string, err error) {
+	body, err = json.Marshal(res)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, recipeBodyETag(res.Version, body), nil
+}
+
+// writeRecipe answers with a recipe and its ETag.
+func writeRecipe(c *gin.Context, status int, res dto.RecipeResponse) {
+	body, etag, err := renderRecipe(res)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render recipe"})
+		return
+	}
+	c.Header("ETag", etag)
+	c.Data(status, "application/json; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header matches the tag. Weak
+// comparison applies, so W/"3" matches "3".
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch is requireIfMatch for writes where the header is optional: without
+// it the write applies to whatever version is current.
+func ifMatch(c *gin.Context) (version *int, ok bool) {
+	if strings.TrimSpace(c.GetHeader("If-Match")) == "" {
+		return nil, true
+	}
+	return requireIfMatch(c)
+}
+
+// requireIfMatch reads the recipe version a write is based on from the
+// If-Match header. "*" accepts any version and yields nil. It answers 428
+// when the header is missing and 412 when it is not a recipe version, and
+// then reports ok=false.
+func requireIfMatch(c *gin.Context) (version *int, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the recipe ETag is required"})
+		return nil, false
+	}
+	if header == "*" {
+		return nil, true
+	}
+
+	// Only strong tags can be used for If-Match.
+	if strings.HasPrefix(header, "W/") {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match does not match the current recipe version"})
+		return nil, false
+	}
+	// Tags of rendered recipes carry the version before a "-".
+	tag, _, _ := strings.Cut(strings.Trim(header, `"`), "-")
+	v, err := strconv.Atoi(tag)
+	if err != nil {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match does not match the current recipe version"})
+		return nil, false
+	}
+	return &v, true
+}
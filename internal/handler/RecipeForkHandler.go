@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ForkRecipe godoc
+// @Summary Fork a recipe
+// @Description Copy another user's recipe, including ingredients, steps and thumbnail, into your account as a private draft that credits the original
+// @Tags Recipes
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 201 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/fork [post]
+func (h *RecipeHandler) ForkRecipe(c *gin.Context) {
+	res, err := h.Service.ForkRecipe(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrRecipeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+// ListForks godoc
+// @Summary List forks of a recipe
+// @Description List the public forks made of a recipe, newest first
+// @Tags Recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param units query string false "Render amounts in this measurement system (metric or us)"
+// @Success 200 {array} dto.RecipeResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/forks [get]
+func (h *RecipeHandler) ListForks(c *gin.Context) {
+	forks, err := h.Service.ListForks(c.Param("id"), c.GetString("userID"))
+	if err != nil {
+		if errors.Is(err, services.ErrRecipeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch forks"})
+		return
+	}
+	c.JSON(http.StatusOK, h.localizeAll(c, forks))
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func imageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecipeNotFound),
+		errors.Is(err, services.ErrImageNotFound),
+		errors.Is(err, services.ErrStepNotFound),
+		errors.Is(err, services.ErrUploadNotFound),
+		errors.Is(err, services.ErrVideoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func imageSaved(c *gin.Context, status int, res dto.RecipeResponse, err error) {
+	if err != nil {
+		imageError(c, err)
+		return
+	}
+	writeRecipe(c, status, res)
+}
+
+// ListImages godoc
+// @Summary List recipe images
+// @Description Gallery images first, then the images attached to steps
+// @Tags Images
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {array} dto.RecipeImageResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/images [get]
+func (h *RecipeHandler) ListImages(c *gin.Context) {
+	list, err := h.Service.ListImages(c.Param("id"), c.GetString("userID"))
+	if err != nil {
+		imageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// AddImage godoc
+// @Summary Upload a recipe image
+// @Description Add an image to the gallery, or to a step when step_id is set. Send the file as image, or the ID of a finished resumable upload as upload_id for images over 2MB. The first gallery image becomes the cover. If-Match is optional; when sent the recipe must still be at that version.
+// @Tags Images
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param image formData file false "Image (jpg, png or webp, at most 2MB)"
+// @Param upload_id formData string false "Finished image upload to use instead of image"
+// @Param alt_text formData string false "Alternative text"
+// @Param step_id formData string false "Step the image belongs to"
+// @Param cover formData bool false "Make the image the cover"
+// @Success 201 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/images [post]
+func (h *RecipeHandler) AddImage(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	uploadID := c.PostForm("upload_id")
+	file, err := c.FormFile("image")
+	if err != nil && uploadID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "image file or upload_id is required"})
+		return
+	}
+	cover := false
+	if v := c.PostForm("cover"); v != "" {
+		if cover, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cover must be true or false"})
+			return
+		}
+	}
+
+	res, err := h.Service.AddImage(c.GetString("userID"), c.Param("id"), version, file, uploadID,
+		c.PostForm("alt_text"), c.PostForm("step_id"), cover)
+	imageSaved(c, http.StatusCreated, res, err)
+}
+
+// UpdateImage godoc
+// @Summary Update a recipe image
+// @Description Change the alt text of an image or move it between the gallery and the steps
+// @Tags Images
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param image_id path string true "Image ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.UpdateImageRequest true "Changes"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/images/{image_id} [put]
+func (h *RecipeHandler) UpdateImage(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.UpdateImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.UpdateImage(c.GetString("userID"), c.Param("id"), c.Param("image_id"), version, req)
+	imageSaved(c, http.StatusOK, res, err)
+}
+
+// DeleteImage godoc
+// @Summary Delete a recipe image
+// @Description Delete an image and its file. When it was the cover, the first gallery image becomes the cover.
+// @Tags Images
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param image_id path string true "Image ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/images/{image_id} [delete]
+func (h *RecipeHandler) DeleteImage(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	res, err := h.Service.RemoveImage(c.GetString("userID"), c.Param("id"), c.Param("image_id"), version)
+	imageSaved(c, http.StatusOK, res, err)
+}
+
+// ReorderImages godoc
+// @Summary Reorder recipe images
+// @Description Put the gallery images, or the images of one step, in the given order. The IDs must list every image of that place exactly once.
+// @Tags Images
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.ReorderRequest true "Image IDs in their new order"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/images/order [put]
+func (h *RecipeHandler) ReorderImages(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.ReorderImages(c.GetString("userID"), c.Param("id"), version, req.IDs)
+	imageSaved(c, http.StatusOK, res, err)
+}
+
+// SetCoverImage godoc
+// @Summary Choose the cover image
+// @Description Make a gallery image the cover of the recipe. The cover is also returned as the thumbnail. Step images cannot be the cover.
+// @Tags Images
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param image_id path string true "Image ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/images/{image_id}/cover [put]
+func (h *RecipeHandler) SetCoverImage(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	res, err := h.Service.SetCoverImage(c.GetString("userID"), c.Param("id"), c.Param("image_id"), version)
+	imageSaved(c, http.StatusOK, res, err)
+}
@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func itemError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecipeNotFound),
+		errors.Is(err, services.ErrIngredientNotFound),
+		errors.Is(err, services.ErrStepNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// itemSaved writes the recipe after an ingredient or step change along with
+// its new ETag.
+func itemSaved(c *gin.Context, status int, res dto.RecipeResponse, err error) {
+	if err != nil {
+		itemError(c, err)
+		return
+	}
+	writeRecipe(c, status, res)
+}
+
+// GetIngredient godoc
+// @Summary Get a recipe ingredient
+// @Tags Ingredients
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param ingredient_id path string true "Ingredient ID"
+// @Success 200 {object} dto.IngredientResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/ingredients/{ingredient_id} [get]
+func (h *RecipeHandler) GetIngredient(c *gin.Context) {
+	res, err := h.Service.GetIngredient(c.Param("id"), c.Param("ingredient_id"), c.GetString("userID"))
+	if err != nil {
+		itemError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// AddIngredient godoc
+// @Summary Add an ingredient
+// @Description Add one ingredient to your recipe, at the given position or at the end. If-Match is optional; when sent the recipe must still be at that version.
+// @Tags Ingredients
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.IngredientRequest true "Ingredient"
+// @Success 201 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/ingredients [post]
+func (h *RecipeHandler) AddIngredient(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.IngredientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.AddIngredient(c.GetString("userID"), c.Param("id"), version, req)
+	itemSaved(c, http.StatusCreated, res, err)
+}
+
+// UpdateIngredient godoc
+// @Summary Update an ingredient
+// @Description Change the fields of one ingredient that are present in the body. A new position moves the ingredient.
+// @Tags Ingredients
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param ingredient_id path string true "Ingredient ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.UpdateIngredientRequest true "Changes"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/ingredients/{ingredient_id} [put]
+func (h *RecipeHandler) UpdateIngredient(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.UpdateIngredientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.UpdateIngredient(c.GetString("userID"), c.Param("id"), c.Param("ingredient_id"), version, req)
+	itemSaved(c, http.StatusOK, res, err)
+}
+
+// DeleteIngredient godoc
+// @Summary Delete an ingredient
+// @Tags Ingredients
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param ingredient_id path string true "Ingredient ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/ingredients/{ingredient_id} [delete]
+func (h *RecipeHandler) DeleteIngredient(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	res, err := h.Service.DeleteIngredient(c.GetString("userID"), c.Param("id"), c.Param("ingredient_id"), version)
+	itemSaved(c, http.StatusOK, res, err)
+}
+
+// ReorderIngredients godoc
+// @Summary Reorder ingredients
+// @Description Put the ingredients in the given order. ids must list every ingredient of the recipe exactly once.
+// @Tags Ingredients
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.ReorderRequest true "Ingredient IDs in the new order"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/ingredients/order [put]
+func (h *RecipeHandler) ReorderIngredients(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.ReorderIngredients(c.GetString("userID"), c.Param("id"), version, req.IDs)
+	itemSaved(c, http.StatusOK, res, err)
+}
+
+// GetStep godoc
+// @Summary Get a recipe step
+// @Tags Steps
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param step_id path string true "Step ID"
+// @Success 200 {object} dto.StepResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/steps/{step_id} [get]
+func (h *RecipeHandler) GetStep(c *gin.Context) {
+	res, err := h.Service.GetStep(c.Param("id"), c.Param("step_id"), c.GetString("userID"))
+	if err != nil {
+		itemError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// AddStep godoc
+// @Summary Add a step
+// @Description Insert a step at the given number, moving the following steps down, or append it
+// @Tags Steps
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.StepRequest true "Step"
+// @Success 201 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/steps [post]
+func (h *RecipeHandler) AddStep(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.StepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.AddStep(c.GetString("userID"), c.Param("id"), version, req)
+	itemSaved(c, http.StatusCreated, res, err)
+}
+
+// UpdateStep godoc
+// @Summary Update a step
+// @Description Change the text of a step, or move it by giving a new number. The step keeps its ID.
+// @Tags Steps
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param step_id path string true "Step ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.UpdateStepRequest true "Changes"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/steps/{step_id} [put]
+func (h *RecipeHandler) UpdateStep(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.UpdateStepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.UpdateStep(c.GetString("userID"), c.Param("id"), c.Param("step_id"), version, req)
+	itemSaved(c, http.StatusOK, res, err)
+}
+
+// DeleteStep godoc
+// @Summary Delete a step
+// @Description Remove a step and renumber the ones after it
+// @Tags Steps
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param step_id path string true "Step ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/steps/{step_id} [delete]
+func (h *RecipeHandler) DeleteStep(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	res, err := h.Service.DeleteStep(c.GetString("userID"), c.Param("id"), c.Param("step_id"), version)
+	itemSaved(c, http.StatusOK, res, err)
+}
+
+// ReorderSteps godoc
+// @Summary Reorder steps
+// @Description Renumber the steps in the given order. ids must list every step of the recipe exactly once.
+// @Tags Steps
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.ReorderRequest true "Step IDs in the new order"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/steps/order [put]
+func (h *RecipeHandler) ReorderSteps(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.ReorderSteps(c.GetString("userID"), c.Param("id"), version, req.IDs)
+	itemSaved(c, http.StatusOK, res, err)
+}
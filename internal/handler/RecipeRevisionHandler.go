@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func revisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecipeNotFound), errors.Is(err, services.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// ListRevisions godoc
+// @Summary List recipe revisions
+// @Description List the revisions of one of your recipes, newest first
+// @Tags Revisions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {array} dto.RevisionResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/revisions [get]
+func (h *RecipeHandler) ListRevisions(c *gin.Context) {
+	revs, err := h.Service.ListRevisions(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, revs)
+}
+
+// GetRevision godoc
+// @Summary Get a recipe revision
+// @Description Retrieve one revision including the recipe content it stores
+// @Tags Revisions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} dto.RevisionResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/revisions/{rev} [get]
+func (h *RecipeHandler) GetRevision(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision number"})
+		return
+	}
+
+	rev, err := h.Service.GetRevision(c.GetString("userID"), c.Param("id"), number)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffRevisions godoc
+// @Summary Compare two recipe revisions
+// @Description Show the changed fields, ingredients (matched by name) and steps (matched by number) between two revisions
+// @Tags Revisions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param from query int true "Older revision number"
+// @Param to query int true "Newer revision number"
+// @Success 200 {object} dto.RevisionDiff
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/revisions/diff [get]
+func (h *RecipeHandler) DiffRevisions(c *gin.Context) {
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be revision numbers"})
+		return
+	}
+
+	diff, err := h.Service.DiffRevisions(c.GetString("userID"), c.Param("id"), from, to)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// RestoreRevision godoc
+// @Summary Restore a recipe revision
+// @Description Put the content of an earlier revision back in place; the restore is recorded as a new revision
+// @Tags Revisions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/revisions/{rev}/restore [post]
+func (h *RecipeHandler) RestoreRevision(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision number"})
+		return
+	}
+
+	res, err := h.Service.RestoreRevision(c.GetString("userID"), c.Param("id"), number)
+	if err != nil {
+		revisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
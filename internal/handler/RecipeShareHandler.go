@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func shareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecipeNotFound), errors.Is(err, services.ErrShareNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// CreateShare godoc
+// @Summary Create a share link
+// @Description Create an unguessable link to an unlisted or public recipe, optionally expiring
+// @Tags Sharing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param request body dto.CreateShareRequest false "Expiry"
+// @Success 201 {object} dto.ShareResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/shares [post]
+func (h *RecipeHandler) CreateShare(c *gin.Context) {
+	var req dto.CreateShareRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+			return
+		}
+	}
+
+	share, err := h.Service.CreateShare(c.GetString("userID"), c.Param("id"), req)
+	if err != nil {
+		shareError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, share)
+}
+
+// ListShares godoc
+// @Summary List share links of a recipe
+// @Tags Sharing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {array} dto.ShareResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/shares [get]
+func (h *RecipeHandler) ListShares(c *gin.Context) {
+	shares, err := h.Service.ListShares(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		shareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+// RevokeShare godoc
+// @Summary Revoke a share link
+// @Tags Sharing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param share_id path string true "Share link ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/shares/{share_id} [delete]
+func (h *RecipeHandler) RevokeShare(c *gin.Context) {
+	if err := h.Service.RevokeShare(c.GetString("userID"), c.Param("id"), c.Param("share_id")); err != nil {
+		shareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "share link revoked"})
+}
+
+// GetSharedRecipe godoc
+// @Summary Open a share link
+// @Description Retrieve the recipe behind a share link; no login required
+// @Tags Sharing
+// @Produce json
+// @Param token path string true "Share token"
+// @Param units query string false "Render amounts in this measurement system (metric or us)"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/shared/{token} [get]
+func (h *RecipeHandler) GetSharedRecipe(c *gin.Context) {
+	res, err := h.Service.GetSharedRecipe(c.Param("token"))
+	if err != nil {
+		shareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.Service.LocalizeRecipe(res, h.unitSystem(c)))
+}
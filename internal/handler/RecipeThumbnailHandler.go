@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func thumbnailError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecipeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// UploadThumbnail godoc
+// @Summary Upload a recipe thumbnail
+// @Description Replace the thumbnail of one of your recipes
+// @Tags Recipes
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param thumbnail formData file true "Thumbnail Image (jpg, png or webp, at most 2MB)"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/thumbnail [put]
+func (h *RecipeHandler) UploadThumbnail(c *gin.Context) {
+	file, err := c.FormFile("thumbnail")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "thumbnail file is required"})
+		return
+	}
+
+	res, err := h.Service.SetThumbnail(c.GetString("userID"), c.Param("id"), file)
+	if err != nil {
+		thumbnailError(c, err)
+		return
+	}
+	writeRecipe(c, http.StatusOK, res)
+}
+
+// DeleteThumbnail godoc
+// @Summary Remove a recipe thumbnail
+// @Tags Recipes
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/recipes/{id}/thumbnail [delete]
+func (h *RecipeHandler) DeleteThumbnail(c *gin.Context) {
+	res, err := h.Service.SetThumbnail(c.GetString("userID"), c.Param("id"), nil)
+	if err != nil {
+		thumbnailError(c, err)
+		return
+	}
+	writeRecipe(c, http.StatusOK, res)
+}
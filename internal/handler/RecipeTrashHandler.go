@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func trashError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRecipeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// ListTrash godoc
+// @Summary List deleted recipes
+// @Description List your recipes in the trash with the time each one will be permanently deleted
+// @Tags Trash
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} dto.TrashedRecipeResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/trash [get]
+func (h *RecipeHandler) ListTrash(c *gin.Context) {
+	list, err := h.Service.ListTrash(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch trash"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// RestoreRecipe godoc
+// @Summary Restore a deleted recipe
+// @Tags Trash
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/trash/{id}/restore [post]
+func (h *RecipeHandler) RestoreRecipe(c *gin.Context) {
+	res, err := h.Service.RestoreRecipe(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		trashError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// PurgeRecipe godoc
+// @Summary Permanently delete a recipe
+// @Description Delete a recipe in the trash and its files right away
+// @Tags Trash
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/trash/{id} [delete]
+func (h *RecipeHandler) PurgeRecipe(c *gin.Context) {
+	if err := h.Service.PurgeRecipe(c.GetString("userID"), c.Param("id")); err != nil {
+		trashError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "recipe permanently deleted"})
+}
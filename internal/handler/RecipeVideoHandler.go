@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/gin-gonic/gin"
+)
+
+// AddVideo godoc
+// @Summary Attach a video to a recipe
+// @Description Attach a finished video upload (upload_id) or a link to YouTube, Vimeo or an allowed video host (url). Duration, size and poster are filled in in the background; the video's status is pending until then. If-Match is optional; when sent the recipe must still be at that version.
+// @Tags Videos
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Param request body dto.VideoInput true "Video"
+// @Success 201 {object} dto.RecipeResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/videos [post]
+func (h *RecipeHandler) AddVideo(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	var req dto.VideoInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	res, err := h.Service.AddVideo(c.GetString("userID"), c.Param("id"), version, req)
+	imageSaved(c, http.StatusCreated, res, err)
+}
+
+// DeleteVideo godoc
+// @Summary Remove a recipe video
+// @Description Detach a video from the recipe; an uploaded file and the poster are deleted once nothing else uses them
+// @Tags Videos
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param video_id path string true "Video ID"
+// @Param If-Match header string false "ETag of the recipe version the change is based on"
+// @Success 200 {object} dto.RecipeResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /api/recipes/{id}/videos/{video_id} [delete]
+func (h *RecipeHandler) DeleteVideo(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	res, err := h.Service.RemoveVideo(c.GetString("userID"), c.Param("id"), c.Param("video_id"), version)
+	imageSaved(c, http.StatusOK, res, err)
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	Service *services.TagService
+}
+
+func NewTagHandler(s *services.TagService) *TagHandler {
+	return &TagHandler{Service: s}
+}
+
+func tagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTagExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// ListTags godoc
+// @Summary List tags with recipe counts
+// @Description List all tags ordered by how many recipes use them, for building a browse UI
+// @Tags Tags
+// @Produce json
+// @Param q query string false "Tag name prefix"
+// @Success 200 {array} dto.TagCountResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/tags [get]
+func (h *TagHandler) ListTags(c *gin.Context) {
+	tags, err := h.Service.ListTags(c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tags"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// CreateTag godoc
+// @Summary Create a tag (admin)
+// @Tags Tags
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body dto.TagRequest true "Tag"
+// @Success 201 {object} dto.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/tags [post]
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var req dto.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	tag, err := h.Service.CreateTag(req.Name)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag godoc
+// @Summary Rename a tag (admin)
+// @Tags Tags
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Param request body dto.TagRequest true "Tag"
+// @Success 200 {object} dto.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/tags/{id} [put]
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	var req dto.TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	tag, err := h.Service.UpdateTag(c.Param("id"), req.Name)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag godoc
+// @Summary Delete a tag (admin)
+// @Description Delete a tag and remove it from every recipe
+// @Tags Tags
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/tags/{id} [delete]
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	if err := h.Service.DeleteTag(c.Param("id")); err != nil {
+		tagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "tag deleted"})
+}
+
+// MergeTag godoc
+// @Summary Merge a tag into another (admin)
+// @Description Move every recipe of the tag onto the target tag and delete the source tag
+// @Tags Tags
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Source tag ID"
+// @Param request body dto.MergeTagRequest true "Target tag"
+// @Success 200 {object} dto.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/tags/{id}/merge [post]
+func (h *TagHandler) MergeTag(c *gin.Context) {
+	var req dto.MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	tag, err := h.Service.MergeTags(c.Param("id"), req.IntoID)
+	if err != nil {
+		tagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/middleware"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type UploadHandler struct {
+	Service *services.UploadService
+}
+
+func NewUploadHandler(s *services.UploadService) *UploadHandler {
+	return &UploadHandler{Service: s}
+}
+
+func uploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUploadOffset):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUploadBusy):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUploadTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUploadType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrInvalidImage):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header: comma separated
+// pairs of a key and a base64 value.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+func uploadHeaders(c *gin.Context, u models.Upload) {
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(u.Length, 10))
+	c.Header("Upload-Expires", u.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// Options godoc
+// @Summary Describe the upload server
+// @Description tus discovery: the protocol version, extensions and maximum size
+// @Tags Uploads
+// @Success 204
+// @Router /api/uploads [options]
+func (h *UploadHandler) Options(c *gin.Context) {
+	c.Header("Tus-Version", middleware.TusVersion)
+	c.Header("Tus-Extension", "creation,termination,expiration")
+	c.Header("Tus-Max-Size", strconv.FormatInt(services.MaxUploadSize(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+// @Summary Start a resumable upload
+// @Description tus creation. Upload-Metadata may carry kind (image or video), filename and filetype. Images may be up to UPLOAD_MAX_IMAGE_MB, videos up to UPLOAD_MAX_VIDEO_MB.
+// @Tags Uploads
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Length header int true "Size of the file in bytes"
+// @Param Upload-Metadata header string false "Comma separated key and base64 value pairs"
+// @Success 201
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Router /api/uploads [post]
+func (h *UploadHandler) CreateUpload(c *gin.Context) {
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Defer-Length is not supported"})
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Length is required"})
+		return
+	}
+	meta, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, err := h.Service.CreateUpload(c.GetString("userID"), length, meta)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	uploadHeaders(c, u)
+	c.Header("Location", "/api/uploads/"+u.ID.String())
+	c.Status(http.StatusCreated)
+}
+
+// UploadOffset godoc
+// @Summary Get the offset of an upload
+// @Description tus HEAD: how many bytes were received, to resume from there
+// @Tags Uploads
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param upload_id path string true "Upload ID"
+// @Success 200
+// @Failure 404
+// @Router /api/uploads/{upload_id} [head]
+func (h *UploadHandler) UploadOffset(c *gin.Context) {
+	u, err := h.Service.GetUpload(c.GetString("userID"), c.Param("upload_id"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	uploadHeaders(c, u)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// WriteChunk godoc
+// @Summary Send a chunk of an upload
+// @Description tus PATCH: append the body at Upload-Offset. The chunk that completes the upload stores the file; images go through the image pipeline.
+// @Tags Uploads
+// @Security BearerAuth
+// @Accept application/offset+octet-stream
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Offset header int true "Bytes received so far"
+// @Param upload_id path string true "Upload ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 423 {object} map[string]string
+// @Router /api/uploads/{upload_id} [patch]
+func (h *UploadHandler) WriteChunk(c *gin.Context) {
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset is required"})
+		return
+	}
+
+	u, err := h.Service.WriteChunk(c.GetString("userID"), c.Param("upload_id"), offset, c.Request.Body, c.Request.ContentLength)
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	uploadHeaders(c, u)
+	c.Status(http.StatusNoContent)
+}
+
+// GetUpload godoc
+// @Summary Get an upload
+// @Description Progress of an upload and, once complete, a link to its file
+// @Tags Uploads
+// @Security BearerAuth
+// @Produce json
+// @Param upload_id path string true "Upload ID"
+// @Success 200 {object} dto.UploadResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/uploads/{upload_id} [get]
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	res, err := h.Service.UploadResponse(c.GetString("userID"), c.Param("upload_id"))
+	if err != nil {
+		uploadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// DeleteUpload godoc
+// @Summary Cancel an upload
+// @Description tus termination: drop the upload and its chunks. Files already attached to recipes are kept.
+// @Tags Uploads
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param upload_id path string true "Upload ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/uploads/{upload_id} [delete]
+func (h *UploadHandler) DeleteUpload(c *gin.Context) {
+	if err := h.Service.DeleteUpload(c.GetString("userID"), c.Param("upload_id")); err != nil {
+		uploadError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
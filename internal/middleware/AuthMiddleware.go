@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -92,3 +93,39 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware authenticates the caller when an Authorization
+// header is sent and lets anonymous requests through otherwise, so handlers
+// can tailor the response to the viewer.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	auth := AuthMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
+
+// StreamAuthMiddleware authenticates a cooking session event stream either
+// with an Authorization header or with a short-lived token for that session
+// in the "token" query parameter, as EventSource cannot send headers.
+func StreamAuthMiddleware() gin.HandlerFunc {
+	auth := AuthMiddleware()
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			auth(c)
+			return
+		}
+		userID, ok := utils.VerifyStreamToken(token, c.Param("session_id"), time.Now())
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream token"})
+			c.Abort()
+			return
+		}
+		c.Set("userID", userID)
+		c.Next()
+	}
+}
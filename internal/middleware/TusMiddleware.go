@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TusVersion is the version of the tus resumable upload protocol served.
+const TusVersion = "1.0.0"
+
+// TusResumable answers upload requests with the protocol version and refuses
+// requests made for another version.
+func TusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", TusVersion)
+		if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != TusVersion {
+			c.Header("Tus-Version", TusVersion)
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "unsupported Tus-Resumable version"})
+			return
+		}
+		c.Next()
+	}
+}
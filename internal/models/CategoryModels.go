@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Category struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	ParentID     *uuid.UUID `gorm:"type:char(36);index" json:"parent_id"`
+	Name         string     `gorm:"type:varchar(50);not null" json:"name"`
+	Slug         string     `gorm:"type:varchar(60);uniqueIndex;not null" json:"slug"`
+	DisplayOrder int        `gorm:"not null;default:0" json:"display_order"`
+
+	Children     []Category            `gorm:"foreignKey:ParentID" json:"children"`
+	Translations []CategoryTranslation `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"translations"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (c *Category) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+// CategoryTranslation is the display name of a category in one locale.
+type CategoryTranslation struct {
+	ID         uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	CategoryID uuid.UUID `gorm:"type:char(36);uniqueIndex:uniq_category_locale,priority:1" json:"category_id"`
+	Locale     string    `gorm:"type:varchar(10);uniqueIndex:uniq_category_locale,priority:2" json:"locale"`
+	Name       string    `gorm:"type:varchar(50);not null" json:"name"`
+}
+
+func (t *CategoryTranslation) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
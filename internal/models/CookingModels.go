@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CookingSession tracks a user cooking a recipe step by step. A session is
+// active until FinishedAt is set; a user has at most one active session per
+// recipe.
+type CookingSession struct {
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:char(36);index;uniqueIndex:uniq_active_cooking_session,priority:1,where:finished_at IS NULL;not null" json:"user_id"`
+	RecipeID    uuid.UUID  `gorm:"type:char(36);index;uniqueIndex:uniq_active_cooking_session,priority:2;not null" json:"recipe_id"`
+	CurrentStep int        `gorm:"not null;default:1" json:"current_step"`
+	FinishedAt  *time.Time `gorm:"index" json:"finished_at"`
+
+	Recipe Recipe         `gorm:"foreignKey:RecipeID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Timers []CookingTimer `gorm:"foreignKey:SessionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"timers"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (s *CookingSession) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+// CookingTimer is a countdown started during a cooking session, usually for
+// one step. It is running until EndsAt unless it was cancelled.
+type CookingTimer struct {
+	ID              uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	SessionID       uuid.UUID  `gorm:"type:char(36);index;not null" json:"session_id"`
+	StepID          *uuid.UUID `gorm:"type:char(36)" json:"step_id"`
+	Label           string     `gorm:"type:varchar(100)" json:"label"`
+	DurationSeconds int        `gorm:"not null" json:"duration_seconds"`
+	EndsAt          time.Time  `gorm:"index;not null" json:"ends_at"`
+	CancelledAt     *time.Time `json:"cancelled_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (t *CookingTimer) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
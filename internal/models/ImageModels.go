@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecipeImage is a picture in a recipe's gallery, or attached to one of its
+// steps when StepID is set. Position orders the images of the gallery and of
+// each step. Key is the storage key of the image, and the cover's key is
+// mirrored in Recipe.Thumbnail.
+type RecipeImage struct {
+	ID       uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	RecipeID uuid.UUID  `gorm:"type:char(36);index;not null" json:"recipe_id"`
+	StepID   *uuid.UUID `gorm:"type:char(36);index" json:"step_id"`
+	Key      string     `gorm:"type:varchar(255);not null" json:"key"`
+	AltText  string     `gorm:"type:varchar(255)" json:"alt_text"`
+	Position int        `gorm:"not null;default:0" json:"position"`
+	IsCover  bool       `gorm:"not null;default:false" json:"is_cover"`
+
+	Placeholder ImagePlaceholder `gorm:"embedded" json:"placeholder"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (i *RecipeImage) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
+
+// ImagePlaceholder is the BlurHash and dominant color of a stored image,
+// computed when it is processed. It is kept on the rows that show the
+// image, so it loads with them; both are empty for images stored before
+// placeholders existed.
+type ImagePlaceholder struct {
+	BlurHash string `gorm:"type:varchar(100);not null;default:''" json:"blur_hash"`
+	Color    string `gorm:"type:varchar(7);not null;default:''" json:"color"`
+}
+
+// StoredFile counts the references to a content-addressed upload. Stem is
+// the key its files share before their "_w<width>.<ext>" suffix and is
+// derived from the SHA-256 of the processed image, so an image uploaded
+// again is stored once and deleted with its last reference.
+type StoredFile struct {
+	Stem     string `gorm:"type:varchar(255);primaryKey" json:"stem"`
+	Size     int64  `gorm:"not null;default:0" json:"size"`
+	RefCount int    `gorm:"not null;default:0" json:"ref_count"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
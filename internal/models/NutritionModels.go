@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NutritionFacts holds energy, macros and key micronutrients. On Food the
+// values are per 100 g; on Recipe they are per serving.
+type NutritionFacts struct {
+	Calories      float64 `json:"calories"`
+	ProteinG      float64 `json:"protein_g"`
+	FatG          float64 `json:"fat_g"`
+	CarbohydrateG float64 `json:"carbohydrate_g"`
+	FiberG        float64 `json:"fiber_g"`
+	SugarsG       float64 `json:"sugars_g"`
+	SodiumMg      float64 `json:"sodium_mg"`
+	CalciumMg     float64 `json:"calcium_mg"`
+	IronMg        float64 `json:"iron_mg"`
+	PotassiumMg   float64 `json:"potassium_mg"`
+	VitaminCMg    float64 `json:"vitamin_c_mg"`
+}
+
+// Food is one entry of the bundled nutrient dataset.
+type Food struct {
+	ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	SourceID    int       `gorm:"uniqueIndex;not null" json:"source_id"`
+	Description string    `gorm:"not null" json:"description"`
+	Aliases     string    `gorm:"type:text" json:"aliases"`
+	PieceGrams  float64   `json:"piece_grams"`
+	Allergens   string    `gorm:"type:varchar(255)" json:"allergens"`
+	DietFlags   string    `gorm:"type:varchar(255)" json:"diet_flags"`
+
+	NutritionFacts `gorm:"embedded"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (f *Food) BeforeCreate(tx *gorm.DB) (err error) {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return
+}
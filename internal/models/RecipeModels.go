@@ -14,22 +14,70 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+const (
+	RecipeStatusDraft     = "draft"
+	RecipeStatusScheduled = "scheduled"
+	RecipeStatusPublished = "published"
+	RecipeStatusArchived  = "archived"
+)
+
+const (
+	VisibilityPrivate  = "private"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPublic   = "public"
+)
+
 type Recipe struct {
-	ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
-	Title       string    `gorm:"not null" json:"title"`
-	Description string    `gorm:"type:text" json:"description"`
-	Category    string    `gorm:"type:varchar(50)" json:"category"`
-	PrepTime    int       `json:"prep_time"`
-	CookTime    int       `json:"cook_time"`
-	Servings    int       `json:"servings"`
-	Thumbnail   string    `gorm:"type:varchar(255)" json:"thumbnail"`
-	UserID      uuid.UUID `gorm:"type:char(36);index" json:"user_id"`
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	Title       string     `gorm:"not null" json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	CategoryID  *uuid.UUID `gorm:"type:char(36);index" json:"category_id"`
+	PrepTime    int        `json:"prep_time"`
+	CookTime    int        `json:"cook_time"`
+	Servings    int        `json:"servings"`
+	Thumbnail   string     `gorm:"type:varchar(255)" json:"thumbnail"`
+	UserID      uuid.UUID  `gorm:"type:char(36);index" json:"user_id"`
+
+	// Only published recipes are visible to other users. Scheduled recipes
+	// are published by the background scheduler once PublishAt has passed.
+	Status      string     `gorm:"type:varchar(20);not null;default:published;index" json:"status"`
+	PublishAt   *time.Time `gorm:"index" json:"publish_at"`
+	PublishedAt *time.Time `json:"published_at"`
+
+	// Private recipes are only visible to the author, unlisted ones also to
+	// holders of an active share link, public ones to everyone once published.
+	Visibility string `gorm:"type:varchar(20);not null;default:public;index" json:"visibility"`
+
+	// ForkedFromID points at the recipe this one was adapted from; ForkCount
+	// caches how many forks were made of this recipe.
+	ForkedFromID *uuid.UUID `gorm:"type:char(36);index" json:"forked_from_id"`
+	ForkCount    int        `gorm:"not null;default:0" json:"fork_count"`
+
+	// Version increases on every change to the recipe and is used as its ETag.
+	Version int `gorm:"not null;default:1" json:"version"`
+
+	// Cached nutrition per serving, recalculated when ingredients change.
+	Nutrition          NutritionFacts `gorm:"embedded;embeddedPrefix:nutrition_" json:"nutrition"`
+	NutritionComplete  bool           `json:"nutrition_complete"`
+	NutritionUpdatedAt *time.Time     `json:"nutrition_updated_at"`
+
+	// Effective allergen and diet labels stored as "|gluten|egg|" so they can
+	// be filtered with LIKE. LabelOverrides is the author's JSON map of
+	// label → present applied on top of the derived labels.
+	Allergens      string `gorm:"type:varchar(255)" json:"allergens"`
+	DietTags       string `gorm:"type:varchar(255)" json:"diet_tags"`
+	LabelOverrides string `gorm:"type:text" json:"label_overrides"`
 
 	// Relations
-	User        User         `gorm:"foreignKey:UserID" json:"user"`
-	Ingredients []Ingredient `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"ingredients"`
-	Steps       []Step       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"steps"`
-	Favorites   []Favorite   `gorm:"foreignKey:RecipeID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"favorites"`
+	User        User          `gorm:"foreignKey:UserID" json:"user"`
+	Category    *Category     `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"category"`
+	ForkedFrom  *Recipe       `gorm:"foreignKey:ForkedFromID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"forked_from,omitempty"`
+	Ingredients []Ingredient  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"ingredients"`
+	Steps       []Step        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"steps"`
+	Favorites   []Favorite    `gorm:"foreignKey:RecipeID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"favorites"`
+	Tags        []Tag         `gorm:"many2many:recipe_tags;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"tags"`
+	Images      []RecipeImage `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"images"`
+	Videos      []RecipeVideo `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"videos"`
 
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -48,6 +96,18 @@ type Ingredient struct {
 	RecipeID uuid.UUID `gorm:"type:char(36);index" json:"recipe_id"`
 	Name     string    `gorm:"not null" json:"name"`
 	Amount   string    `gorm:"not null" json:"amount"`
+	Quantity *float64  `json:"quantity"`
+	Unit     string    `gorm:"type:varchar(30)" json:"unit"`
+	Position int       `gorm:"not null;default:0" json:"position"`
+
+	// Group optionally names the part of the recipe the ingredient belongs
+	// to, such as "For the sambal". Ingredients of a group are kept together.
+	Group string `gorm:"column:group_name;type:varchar(100)" json:"group"`
+
+	// FoodID links the ingredient to the nutrient dataset. FoodManual marks
+	// an author override that auto-matching must not replace.
+	FoodID     *uuid.UUID `gorm:"type:char(36);index" json:"food_id"`
+	FoodManual bool       `json:"food_manual"`
 }
 
 func (i *Ingredient) BeforeCreate(tx *gorm.DB) (err error) {
@@ -62,6 +122,20 @@ type Step struct {
 	RecipeID uuid.UUID `gorm:"type:char(36);index" json:"recipe_id"`
 	Number   int       `gorm:"not null" json:"number"`
 	Detail   string    `gorm:"type:text;not null" json:"detail"`
+
+	// Section optionally names the part of the method the step belongs to.
+	// Steps of a section are numbered consecutively.
+	Section string `gorm:"type:varchar(100)" json:"section"`
+
+	// DurationSeconds and Temperature are set by the author. When they are
+	// missing they are read from Detail, e.g. "masak 15 menit" or "180°C".
+	// TemperatureUnit is "C" or "F".
+	DurationSeconds *int     `json:"duration_seconds"`
+	Temperature     *float64 `json:"temperature"`
+	TemperatureUnit string   `gorm:"type:varchar(1)" json:"temperature_unit"`
+
+	// Deleting a step moves its images back to the gallery.
+	Images []RecipeImage `gorm:"foreignKey:StepID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"images"`
 }
 
 func (s *Step) BeforeCreate(tx *gorm.DB) (err error) {
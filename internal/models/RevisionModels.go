@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecipeRevision is an immutable JSON snapshot of a recipe's content taken
+// after every create, update and restore. Number counts up from 1 per recipe.
+type RecipeRevision struct {
+	ID       uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	RecipeID uuid.UUID `gorm:"type:char(36);uniqueIndex:uniq_recipe_revision,priority:1;not null" json:"recipe_id"`
+	Number   int       `gorm:"uniqueIndex:uniq_recipe_revision,priority:2;not null" json:"number"`
+	UserID   uuid.UUID `gorm:"type:char(36);index" json:"user_id"`
+	Snapshot string    `gorm:"type:text;not null" json:"snapshot"`
+
+	Recipe Recipe `gorm:"foreignKey:RecipeID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *RecipeRevision) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecipeShare is a share link that lets anyone holding the token view an
+// unlisted recipe until it expires or is revoked.
+type RecipeShare struct {
+	ID        uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	RecipeID  uuid.UUID  `gorm:"type:char(36);index;not null" json:"recipe_id"`
+	Token     string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"token"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+
+	Recipe Recipe `gorm:"foreignKey:RecipeID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *RecipeShare) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+// Active reports whether the share link can still be used.
+func (s RecipeShare) Active(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || s.ExpiresAt.After(now))
+}
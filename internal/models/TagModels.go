@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Tag struct {
+	ID   uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	Name string    `gorm:"type:varchar(50);not null" json:"name"`
+	Slug string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"slug"`
+
+	Recipes []Recipe `gorm:"many2many:recipe_tags;" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (t *Tag) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
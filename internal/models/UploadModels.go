@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	UploadKindImage = "image"
+	UploadKindVideo = "video"
+)
+
+// Upload is a resumable upload. Its bytes arrive in chunks stored as
+// separate objects until Offset reaches Length; the finished file is then
+// stored and Key is set. Until it expires the upload holds a reference on
+// Key, so the file can be attached to recipes.
+type Upload struct {
+	ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID      uuid.UUID `gorm:"type:char(36);index;not null" json:"user_id"`
+	Kind        string    `gorm:"type:varchar(20);not null" json:"kind"`
+	Filename    string    `gorm:"type:varchar(255)" json:"filename"`
+	ContentType string    `gorm:"type:varchar(100)" json:"content_type"`
+	Length      int64     `gorm:"not null" json:"length"`
+	Offset      int64     `gorm:"not null;default:0" json:"offset"`
+	Chunks      int       `gorm:"not null;default:0" json:"chunks"`
+	Key         string    `gorm:"type:varchar(255)" json:"key"`
+	ExpiresAt   time.Time `gorm:"index;not null" json:"expires_at"`
+
+	// ClaimID marks the PATCH that is writing the upload's next bytes, so
+	// two requests cannot write at the same offset.
+	ClaimID   *uuid.UUID `gorm:"type:char(36)" json:"-"`
+	ClaimedAt *time.Time `json:"-"`
+
+	Placeholder ImagePlaceholder `gorm:"embedded" json:"placeholder"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (u *Upload) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Processing states of a recipe video.
+const (
+	VideoStatusPending    = "pending"
+	VideoStatusProcessing = "processing"
+	VideoStatusReady      = "ready"
+	VideoStatusFailed     = "failed"
+)
+
+// RecipeVideo is a video attached to a recipe: either an uploaded file,
+// stored under Key, or a link to an allowed video site in ExternalURL.
+// Duration, size and the poster frame are filled in by the video processor
+// in the background; until then Status is pending.
+type RecipeVideo struct {
+	ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	RecipeID    uuid.UUID `gorm:"type:char(36);index;not null" json:"recipe_id"`
+	Key         string    `gorm:"type:varchar(255)" json:"key"`
+	ExternalURL string    `gorm:"type:varchar(500)" json:"external_url"`
+	Provider    string    `gorm:"type:varchar(50)" json:"provider"`
+	Title       string    `gorm:"type:varchar(255)" json:"title"`
+	Position    int       `gorm:"not null;default:0" json:"position"`
+
+	Status          string     `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	Attempts        int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt   *time.Time `gorm:"index" json:"next_attempt_at"`
+	Error           string     `gorm:"type:varchar(500)" json:"error"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	Width           int        `json:"width"`
+	Height          int        `json:"height"`
+	PosterKey       string     `gorm:"type:varchar(255)" json:"poster_key"`
+
+	PosterPlaceholder ImagePlaceholder `gorm:"embedded;embeddedPrefix:poster_" json:"poster_placeholder"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (v *RecipeVideo) BeforeCreate(tx *gorm.DB) (err error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return
+}
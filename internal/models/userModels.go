@@ -7,15 +7,25 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID       uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
-	Name     string    `gorm:"not null" json:"name"`
-	Email    string    `gorm:"unique;not null" json:"email"`
-	Password string    `gorm:"not null" json:"-"`
-	Bio      string    `gorm:"type:text" json:"bio"`
-	Avatar   string    `gorm:"type:text" json:"avatar"`
-	Banner   string    `gorm:"type:text" json:"banner"`
-	
+	ID         uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+	Name       string    `gorm:"not null" json:"name"`
+	Email      string    `gorm:"unique;not null" json:"email"`
+	Password   string    `gorm:"not null" json:"-"`
+	Bio        string    `gorm:"type:text" json:"bio"`
+	Avatar     string    `gorm:"type:text" json:"avatar"`
+	Banner     string    `gorm:"type:text" json:"banner"`
+	UnitSystem string    `gorm:"type:varchar(10);default:metric" json:"unit_system"`
+	Role       string    `gorm:"type:varchar(20);default:user" json:"role"`
+
+	AvatarPlaceholder ImagePlaceholder `gorm:"embedded;embeddedPrefix:avatar_" json:"avatar_placeholder"`
+	BannerPlaceholder ImagePlaceholder `gorm:"embedded;embeddedPrefix:banner_" json:"banner_placeholder"`
+
 	Recipes   []Recipe   `gorm:"foreignKey:UserID" json:"recipes"`
 	Favorites []Favorite `gorm:"foreignKey:UserID" json:"favorites"`
 
@@ -8,6 +8,7 @@ import (
 	"github.com/bayuTri-Code/BE-Recipe/internal/handler"
 	"github.com/bayuTri-Code/BE-Recipe/internal/middleware"
 	"github.com/bayuTri-Code/BE-Recipe/internal/services"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -22,9 +23,9 @@ func Routes(db *gorm.DB) *gin.Engine {
 	originallow := strings.Split(os.Getenv("CORS_ORIGINS"), ",")
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     originallow,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", "If-None-Match", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Defer-Length"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Expires"},
 		AllowCredentials: true,
 	}))
 
@@ -39,9 +40,20 @@ func Routes(db *gorm.DB) *gin.Engine {
 		})
 	})
 
-	r.Static("/storage", "./public/storage")
-	r.Static("/profile-storage", "./public/profile_storage")
-	r.Static("/profile-banner", "./public/profile_banner")
+	// Stored files are served from /media so private images can be held
+	// back unless the link is signed. The older paths keep links that were
+	// handed out before working.
+	mediaHandler := handler.NewMediaHandler(services.NewMediaService(db))
+	for _, route := range []struct{ path, prefix string }{
+		{"/storage", "storage"},
+		{"/profile-storage", "profile_storage"},
+		{"/profile-banner", "profile_banner"},
+	} {
+		r.GET(route.path+"/*key", mediaHandler.Legacy(route.prefix))
+		r.HEAD(route.path+"/*key", mediaHandler.Legacy(route.prefix))
+	}
+	r.GET(storage.MediaPath+"/*key", mediaHandler.Serve)
+	r.HEAD(storage.MediaPath+"/*key", mediaHandler.Serve)
 
 	// Auth routes
 	auth := r.Group("/auth")
@@ -57,25 +69,137 @@ func Routes(db *gorm.DB) *gin.Engine {
 	// Recipe & Favorite routes
 	recipeService := services.NewRecipeService(db)
 	favoriteService := services.NewFavoriteService(db)
+	nutritionService := recipeService.Nutrition
 
 	recipeHandler := handler.NewRecipeHandler(recipeService)
 	favoriteHandler := handler.NewFavoriteHandler(favoriteService)
+	nutritionHandler := handler.NewNutritionHandler(nutritionService)
 
 	apiRecipe := r.Group("/api")
 	{
 		apiRecipe.GET("/recipes", recipeHandler.GetAllRecipes)
-		apiRecipe.GET("/recipesByCategory", handler.GetRecipesByCategory)
+		apiRecipe.GET("/recipesByCategory", recipeHandler.GetRecipesByCategory)
 
 		apiRecipe.GET("/myrecipes", middleware.AuthMiddleware(), recipeHandler.GetMyRecipes)
-		apiRecipe.GET("/recipes/:id", middleware.AuthMiddleware(), recipeHandler.GetRecipeByID)
+		apiRecipe.GET("/recipes/:id", middleware.OptionalAuthMiddleware(), recipeHandler.GetRecipeByID)
 		apiRecipe.POST("/recipes", middleware.AuthMiddleware(), middleware.RateLimiter(5, 60), recipeHandler.CreateRecipe)
 		apiRecipe.PUT("/recipes/:id", middleware.AuthMiddleware(),  middleware.RateLimiter(10, 60), recipeHandler.UpdateRecipe)
+		apiRecipe.PATCH("/recipes/:id", middleware.AuthMiddleware(), middleware.RateLimiter(10, 60), recipeHandler.PatchRecipe)
 		apiRecipe.DELETE("/recipes/:id", middleware.AuthMiddleware(), middleware.RateLimiter(15, 60), recipeHandler.DeleteRecipe)
+		apiRecipe.PUT("/recipes/:id/thumbnail", middleware.AuthMiddleware(), middleware.RateLimiter(10, 60), recipeHandler.UploadThumbnail)
+		apiRecipe.DELETE("/recipes/:id/thumbnail", middleware.AuthMiddleware(), middleware.RateLimiter(10, 60), recipeHandler.DeleteThumbnail)
+
+		// Images
+		apiRecipe.GET("/recipes/:id/images", middleware.OptionalAuthMiddleware(), recipeHandler.ListImages)
+		apiRecipe.POST("/recipes/:id/images", middleware.AuthMiddleware(), middleware.RateLimiter(10, 60), recipeHandler.AddImage)
+		apiRecipe.PUT("/recipes/:id/images/order", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.ReorderImages)
+		apiRecipe.PUT("/recipes/:id/images/:image_id", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.UpdateImage)
+		apiRecipe.DELETE("/recipes/:id/images/:image_id", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.DeleteImage)
+		apiRecipe.PUT("/recipes/:id/images/:image_id/cover", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.SetCoverImage)
+
+		// Videos
+		apiRecipe.POST("/recipes/:id/videos", middleware.AuthMiddleware(), middleware.RateLimiter(10, 60), recipeHandler.AddVideo)
+		apiRecipe.DELETE("/recipes/:id/videos/:video_id", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.DeleteVideo)
 
 		// Favorites
 		apiRecipe.GET("/recipes/favorites", middleware.AuthMiddleware(), favoriteHandler.GetAllFavorites)
-		apiRecipe.POST("/recipes/:recipe_id/favorites", middleware.AuthMiddleware(), middleware.RateLimiter(20, 60), favoriteHandler.AddFavoriteHandler)
+		apiRecipe.POST("/recipes/:id/favorites", middleware.AuthMiddleware(), middleware.RateLimiter(20, 60), favoriteHandler.AddFavoriteHandler)
 		// apiRecipe.DELETE("/recipes/:id/favorites/:user_id", favoriteHandler.RemoveFavorite)
+
+		// Trash
+		apiRecipe.GET("/trash", middleware.AuthMiddleware(), recipeHandler.ListTrash)
+		apiRecipe.POST("/trash/:id/restore", middleware.AuthMiddleware(), middleware.RateLimiter(15, 60), recipeHandler.RestoreRecipe)
+		apiRecipe.DELETE("/trash/:id", middleware.AuthMiddleware(), middleware.RateLimiter(15, 60), recipeHandler.PurgeRecipe)
+
+		// Forks
+		apiRecipe.GET("/recipes/:id/forks", middleware.OptionalAuthMiddleware(), recipeHandler.ListForks)
+		apiRecipe.POST("/recipes/:id/fork", middleware.AuthMiddleware(), middleware.RateLimiter(10, 60), recipeHandler.ForkRecipe)
+
+		// Revisions
+		apiRecipe.GET("/recipes/:id/revisions", middleware.AuthMiddleware(), recipeHandler.ListRevisions)
+		apiRecipe.GET("/recipes/:id/revisions/diff", middleware.AuthMiddleware(), recipeHandler.DiffRevisions)
+		apiRecipe.GET("/recipes/:id/revisions/:rev", middleware.AuthMiddleware(), recipeHandler.GetRevision)
+		apiRecipe.POST("/recipes/:id/revisions/:rev/restore", middleware.AuthMiddleware(), middleware.RateLimiter(10, 60), recipeHandler.RestoreRevision)
+
+		// Share links
+		apiRecipe.GET("/shared/:token", recipeHandler.GetSharedRecipe)
+		apiRecipe.GET("/recipes/:id/shares", middleware.AuthMiddleware(), recipeHandler.ListShares)
+		apiRecipe.POST("/recipes/:id/shares", middleware.AuthMiddleware(), middleware.RateLimiter(20, 60), recipeHandler.CreateShare)
+		apiRecipe.DELETE("/recipes/:id/shares/:share_id", middleware.AuthMiddleware(), recipeHandler.RevokeShare)
+
+		// Ingredients and steps
+		apiRecipe.GET("/recipes/:id/ingredients/:ingredient_id", middleware.OptionalAuthMiddleware(), recipeHandler.GetIngredient)
+		apiRecipe.POST("/recipes/:id/ingredients", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.AddIngredient)
+		apiRecipe.PUT("/recipes/:id/ingredients/order", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.ReorderIngredients)
+		apiRecipe.PUT("/recipes/:id/ingredients/:ingredient_id", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.UpdateIngredient)
+		apiRecipe.DELETE("/recipes/:id/ingredients/:ingredient_id", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.DeleteIngredient)
+		apiRecipe.GET("/recipes/:id/steps/:step_id", middleware.OptionalAuthMiddleware(), recipeHandler.GetStep)
+		apiRecipe.POST("/recipes/:id/steps", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.AddStep)
+		apiRecipe.PUT("/recipes/:id/steps/order", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.ReorderSteps)
+		apiRecipe.PUT("/recipes/:id/steps/:step_id", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.UpdateStep)
+		apiRecipe.DELETE("/recipes/:id/steps/:step_id", middleware.AuthMiddleware(), middleware.RateLimiter(30, 60), recipeHandler.DeleteStep)
+
+		apiRecipe.PUT("/recipes/:id/labels", middleware.AuthMiddleware(), middleware.RateLimiter(20, 60), recipeHandler.SetLabelOverrides)
+
+		// Nutrition
+		apiRecipe.GET("/foods", nutritionHandler.SearchFoods)
+		apiRecipe.PUT("/recipes/:id/ingredients/:ingredient_id/food", middleware.AuthMiddleware(), middleware.RateLimiter(20, 60), nutritionHandler.SetIngredientFood)
+	}
+
+	// Resumable uploads (tus)
+	uploadHandler := handler.NewUploadHandler(services.NewUploadService(db))
+	r.OPTIONS("/api/uploads", middleware.TusResumable(), uploadHandler.Options)
+	apiUploads := r.Group("/api/uploads")
+	apiUploads.Use(middleware.AuthMiddleware())
+	{
+		apiUploads.POST("", middleware.TusResumable(), middleware.RateLimiter(20, 60), uploadHandler.CreateUpload)
+		apiUploads.HEAD("/:upload_id", middleware.TusResumable(), uploadHandler.UploadOffset)
+		apiUploads.PATCH("/:upload_id", middleware.TusResumable(), uploadHandler.WriteChunk)
+		apiUploads.DELETE("/:upload_id", middleware.TusResumable(), uploadHandler.DeleteUpload)
+		apiUploads.GET("/:upload_id", uploadHandler.GetUpload)
+	}
+
+	// Cooking mode
+	cookingHandler := handler.NewCookingHandler(services.NewCookingService(db))
+
+	r.POST("/api/recipes/:id/cook", middleware.AuthMiddleware(), middleware.RateLimiter(20, 60), cookingHandler.StartSession)
+
+	apiCooking := r.Group("/api/cooking-sessions")
+	apiCooking.Use(middleware.AuthMiddleware())
+	{
+		apiCooking.GET("", cookingHandler.ListSessions)
+		apiCooking.GET("/:session_id", cookingHandler.GetSession)
+		apiCooking.DELETE("/:session_id", cookingHandler.FinishSession)
+		apiCooking.PUT("/:session_id/step", middleware.RateLimiter(120, 60), cookingHandler.SetStep)
+		apiCooking.POST("/:session_id/timers", middleware.RateLimiter(60, 60), cookingHandler.StartTimer)
+		apiCooking.DELETE("/:session_id/timers/:timer_id", cookingHandler.CancelTimer)
+		apiCooking.POST("/:session_id/events/token", middleware.RateLimiter(30, 60), cookingHandler.StreamToken)
+	}
+	// EventSource cannot send an Authorization header, so the event stream
+	// also takes a short-lived token from /events/token in the query.
+	r.GET("/api/cooking-sessions/:session_id/events", middleware.StreamAuthMiddleware(), cookingHandler.SessionEvents)
+
+	// Tag routes
+	tagHandler := handler.NewTagHandler(services.NewTagService(db))
+
+	apiTag := r.Group("/api/tags")
+	{
+		apiTag.GET("", tagHandler.ListTags)
+		apiTag.POST("", middleware.AuthMiddleware(), middleware.AdminMiddleware(), tagHandler.CreateTag)
+		apiTag.PUT("/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), tagHandler.UpdateTag)
+		apiTag.DELETE("/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), tagHandler.DeleteTag)
+		apiTag.POST("/:id/merge", middleware.AuthMiddleware(), middleware.AdminMiddleware(), tagHandler.MergeTag)
+	}
+
+	// Category routes
+	categoryHandler := handler.NewCategoryHandler(services.NewCategoryService(db))
+
+	apiCategory := r.Group("/api/categories")
+	{
+		apiCategory.GET("", categoryHandler.ListCategories)
+		apiCategory.POST("", middleware.AuthMiddleware(), middleware.AdminMiddleware(), categoryHandler.CreateCategory)
+		apiCategory.PUT("/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), categoryHandler.UpdateCategory)
+		apiCategory.DELETE("/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), categoryHandler.DeleteCategory)
 	}
 
 	// Dashboard routes
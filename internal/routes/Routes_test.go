@@ -0,0 +1,20 @@
+package routes
+
+import "testing"
+
+// Gin panics while registering conflicting routes, such as two different
+// wildcard names at the same position, so building the engine is enough to
+// catch them.
+func TestRoutesRegister(t *testing.T) {
+	t.Setenv("CORS_ORIGINS", "http://localhost:5173")
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("registering routes panicked: %v", r)
+		}
+	}()
+
+	r := Routes(nil)
+	if len(r.Routes()) == 0 {
+		t.Fatal("no routes registered")
+	}
+}
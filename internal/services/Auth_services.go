@@ -7,8 +7,8 @@ import (
 	"log"
 	"mime/multipart"
 	"os"
-	"path/filepath"
 	"strings"
+	"testing"
 	"time"
 
 	"github.com/bayuTri-Code/BE-Recipe/database"
@@ -36,29 +36,13 @@ func init() {
 		log.Println("File .env is not found")
 	}
 	secret := os.Getenv("ACCESS_TOKEN_SECRET")
-	if secret == "" {
+	// Tests build the services without a configured environment.
+	if secret == "" && !testing.Testing() {
 		log.Fatal("ACCESS_TOKEN_SECRET not set in environment")
 	}
 	jwtSecret = []byte(secret)
 }
 
-func saveUploadedFile(file *multipart.FileHeader, dst string) error {
-	src, err := file.Open()
-	if err != nil {
-		return err
-	}
-	defer src.Close()
-
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = out.ReadFrom(src)
-	return err
-}
-
 func RegisterServices(ctx context.Context, Name, Email, Password string) (*models.User, error) {
 	db := database.Db
 	Email = strings.ToLower(strings.TrimSpace(Email))
@@ -123,7 +107,21 @@ func GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func UpdateProfile(userID uuid.UUID, name, email, bio string, avatarFile, bannerFile *multipart.FileHeader) (*dto.UpdateProfileResponse, error) {
+// IsAdmin reports whether the user has the admin role.
+func IsAdmin(userID string) (bool, error) {
+	var user models.User
+	if err := database.Db.Select("role").First(&user, "id = ?", userID).Error; err != nil {
+		return false, err
+	}
+	return user.Role == models.RoleAdmin, nil
+}
+
+func UpdateProfile(userID uuid.UUID, name, email, bio, unitSystem string, avatarFile, bannerFile *multipart.FileHeader) (res *dto.UpdateProfileResponse, err error) {
+	// New files are dropped if the update fails; the old ones only once it
+	// is saved.
+	var files fileChanges
+	defer func() { files.finish(err) }()
+
 	var user models.User
 	if err := database.Db.First(&user, "id = ?", userID).Error; err != nil {
 		return nil, errors.New("user not found")
@@ -138,58 +136,49 @@ func UpdateProfile(userID uuid.UUID, name, email, bio string, avatarFile, banner
 	if bio != "" {
 		user.Bio = bio
 	}
-
-	apiImagePath := os.Getenv("API_IMAGE_PATH")
-
-
-	if avatarFile != nil {
-		uploadDir := "public/profile_storage"
-		if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-			return nil, errors.New("failed to create avatar storage directory")
+	if unitSystem != "" {
+		if !utils.IsValidUnitSystem(unitSystem) {
+			return nil, errors.New("unit_system must be metric or us")
 		}
+		user.UnitSystem = unitSystem
+	}
 
-		if user.Avatar != "" {
-			_ = os.Remove(filepath.Join(uploadDir, filepath.Base(user.Avatar)))
-		}
-		
+	if avatarFile != nil {
 		if avatarFile.Size > 2*1024*1024 {
 			return nil, errors.New("avatar file size must not exceed 2MB")
 		}
 
-		fileExt := filepath.Ext(avatarFile.Filename)
-		newFileName := fmt.Sprintf("%s%s", uuid.NewString(), fileExt)
-		filePath := filepath.Join(uploadDir, newFileName)
-
-		if err := saveUploadedFile(avatarFile, filePath); err != nil {
+		key, placeholder, err := storeUpload(avatarFile)
+		if errors.Is(err, utils.ErrInvalidImage) {
+			return nil, err
+		}
+		if err != nil {
 			return nil, errors.New("failed to save avatar file")
 		}
 
-		user.Avatar = fmt.Sprintf("%s/profile-storage/%s", apiImagePath, newFileName)
+		files.add(key)
+		files.replace(user.Avatar)
+		user.Avatar = key
+		user.AvatarPlaceholder = placeholder
 	}
 
 	if bannerFile != nil {
-		uploadDir := "public/profile_banner"
-		if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-			return nil, errors.New("failed to create banner storage directory")
-		}
-
-		if user.Banner != "" {
-			_ = os.Remove(filepath.Join(uploadDir, filepath.Base(user.Banner)))
-		}
-
 		if bannerFile.Size > 2*1024*1024 {
-			return nil, errors.New("avatar file size must not exceed 2MB")
+			return nil, errors.New("banner file size must not exceed 2MB")
 		}
 
-		fileExt := filepath.Ext(bannerFile.Filename)
-		newFileName := fmt.Sprintf("%s%s", uuid.NewString(), fileExt)
-		filePath := filepath.Join(uploadDir, newFileName)
-
-		if err := saveUploadedFile(bannerFile, filePath); err != nil {
+		key, placeholder, err := storeUpload(bannerFile)
+		if errors.Is(err, utils.ErrInvalidImage) {
+			return nil, err
+		}
+		if err != nil {
 			return nil, errors.New("failed to save banner file")
 		}
 
-		user.Banner = fmt.Sprintf("%s/profile-banner/%s", apiImagePath, newFileName)
+		files.add(key)
+		files.replace(user.Banner)
+		user.Banner = key
+		user.BannerPlaceholder = placeholder
 	}
 
 	if err := database.Db.Save(&user).Error; err != nil {
@@ -197,12 +186,19 @@ func UpdateProfile(userID uuid.UUID, name, email, bio string, avatarFile, banner
 	}
 
 	return &dto.UpdateProfileResponse{
-		UserId: user.ID.String(),
-		Name:   user.Name,
-		Email:  user.Email,
-		Bio:    user.Bio,
-		Avatar: user.Avatar,
-		Banner: user.Banner,
+		UserId:     user.ID.String(),
+		Name:       user.Name,
+		Email:      user.Email,
+		Bio:        user.Bio,
+		Avatar:     mediaURL(user.Avatar, false),
+		Banner:     mediaURL(user.Banner, false),
+		UnitSystem: user.UnitSystem,
+
+		AvatarVariants: mediaVariants(user.Avatar, false),
+		BannerVariants: mediaVariants(user.Banner, false),
+
+		AvatarPlaceholder: toPlaceholder(user.AvatarPlaceholder),
+		BannerPlaceholder: toPlaceholder(user.BannerPlaceholder),
 	}, nil
 }
 
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("a category with this slug already exists")
+	ErrCategoryInUse    = errors.New("category still has recipes or subcategories, pass move_to to reassign them")
+)
+
+type CategoryService struct {
+	DB *gorm.DB
+}
+
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{DB: db}
+}
+
+// NormalizeLocale lowercases a locale and keeps only the first entry of an
+// Accept-Language header: "id-ID,id;q=0.9" → "id-id".
+func NormalizeLocale(locale string) string {
+	locale, _, _ = strings.Cut(locale, ",")
+	locale, _, _ = strings.Cut(locale, ";")
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// localizedName picks the translation for the locale, falling back to the
+// base language ("id" for "id-id") and then to the default name.
+func localizedName(c models.Category, locale string) string {
+	if locale == "" {
+		return c.Name
+	}
+	base, _, _ := strings.Cut(locale, "-")
+	name := c.Name
+	for _, t := range c.Translations {
+		if t.Locale == locale {
+			return t.Name
+		}
+		if t.Locale == base {
+			name = t.Name
+		}
+	}
+	return name
+}
+
+func toCategoryTree(all []models.Category, parentID *uuid.UUID, locale string) []dto.CategoryResponse {
+	out := []dto.CategoryResponse{}
+	for _, c := range all {
+		if (c.ParentID == nil) != (parentID == nil) || (parentID != nil && *c.ParentID != *parentID) {
+			continue
+		}
+		id := c.ID
+		translations := map[string]string{}
+		for _, t := range c.Translations {
+			translations[t.Locale] = t.Name
+		}
+		out = append(out, dto.CategoryResponse{
+			ID:           c.ID,
+			ParentID:     c.ParentID,
+			Name:         localizedName(c, locale),
+			Slug:         c.Slug,
+			DisplayOrder: c.DisplayOrder,
+			Translations: translations,
+			Children:     toCategoryTree(all, &id, locale),
+		})
+	}
+	return out
+}
+
+func loadCategories(db *gorm.DB) ([]models.Category, error) {
+	var all []models.Category
+	err := db.Preload("Translations").
+		Order("display_order ASC, name ASC").
+		Find(&all).Error
+	return all, err
+}
+
+// descendantIDs returns the ID of the root category and of every category
+// below it.
+func descendantIDs(all []models.Category, root uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{root}
+	for i := 0; i < len(ids); i++ {
+		for _, c := range all {
+			if c.ParentID != nil && *c.ParentID == ids[i] {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// findCategory looks a category up by ID, slug, or default or localized
+// name, so "Makanan" finds the "food" category when it has that translation.
+func findCategory(db *gorm.DB, ref string) (models.Category, error) {
+	var c models.Category
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return c, ErrCategoryNotFound
+	}
+
+	if id, err := uuid.Parse(ref); err == nil {
+		if err := db.First(&c, "id = ?", id).Error; err == nil {
+			return c, nil
+		}
+	}
+	if err := db.First(&c, "slug = ?", utils.Slugify(ref)).Error; err == nil {
+		return c, nil
+	}
+
+	var t models.CategoryTranslation
+	if err := db.First(&t, "LOWER(name) = LOWER(?)", ref).Error; err == nil {
+		if err := db.First(&c, "id = ?", t.CategoryID).Error; err == nil {
+			return c, nil
+		}
+	}
+	if err := db.First(&c, "LOWER(name) = LOWER(?)", ref).Error; err == nil {
+		return c, nil
+	}
+	return c, ErrCategoryNotFound
+}
+
+// resolveRecipeCategory turns the category an author sent with a recipe into
+// a category ID. An empty value clears the category.
+func resolveRecipeCategory(tx *gorm.DB, ref string) (*uuid.UUID, error) {
+	if strings.TrimSpace(ref) == "" {
+		return nil, nil
+	}
+	c, err := findCategory(tx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("unknown category %q", ref)
+	}
+	return &c.ID, nil
+}
+
+// applyCategoryFilter keeps recipes in the requested category or any of its
+// subcategories. An unknown category matches nothing.
+func applyCategoryFilter(db *gorm.DB, filter dto.RecipeFilter) *gorm.DB {
+	if strings.TrimSpace(filter.Category) == "" {
+		return db
+	}
+
+	session := db.Session(&gorm.Session{NewDB: true})
+	c, err := findCategory(session, filter.Category)
+	if err != nil {
+		return db.Where("1 = 0")
+	}
+	all, err := loadCategories(session)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	return db.Where("recipes.category_id IN ?", descendantIDs(all, c.ID))
+}
+
+// ListCategories returns the category tree with names in the given locale.
+func (s *CategoryService) ListCategories(locale string) ([]dto.CategoryResponse, error) {
+	all, err := loadCategories(s.DB)
+	if err != nil {
+		return nil, err
+	}
+	return toCategoryTree(all, nil, NormalizeLocale(locale)), nil
+}
+
+func (s *CategoryService) categoryResponse(tx *gorm.DB, id uuid.UUID) (dto.CategoryResponse, error) {
+	all, err := loadCategories(tx)
+	if err != nil {
+		return dto.CategoryResponse{}, err
+	}
+	for _, c := range all {
+		if c.ID == id {
+			tree := toCategoryTree([]models.Category{c}, c.ParentID, "")
+			tree[0].Children = toCategoryTree(all, &c.ID, "")
+			return tree[0], nil
+		}
+	}
+	return dto.CategoryResponse{}, ErrCategoryNotFound
+}
+
+// checkParent makes sure the parent exists and that moving the category
+// under it would not create a cycle.
+func checkParent(tx *gorm.DB, id uuid.UUID, parentID *uuid.UUID) error {
+	if parentID == nil {
+		return nil
+	}
+	var parent models.Category
+	if err := tx.First(&parent, "id = ?", *parentID).Error; err != nil {
+		return errors.New("parent category not found")
+	}
+	if id == uuid.Nil {
+		return nil
+	}
+	all, err := loadCategories(tx)
+	if err != nil {
+		return err
+	}
+	for _, d := range descendantIDs(all, id) {
+		if d == *parentID {
+			return errors.New("a category cannot be moved under itself or its subcategories")
+		}
+	}
+	return nil
+}
+
+func setTranslations(tx *gorm.DB, categoryID uuid.UUID, translations map[string]string) error {
+	if err := tx.Where("category_id = ?", categoryID).Delete(&models.CategoryTranslation{}).Error; err != nil {
+		return err
+	}
+	locales := make([]string, 0, len(translations))
+	for locale := range translations {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	for _, locale := range locales {
+		name := strings.TrimSpace(translations[locale])
+		locale = NormalizeLocale(locale)
+		if locale == "" || name == "" {
+			continue
+		}
+		t := models.CategoryTranslation{CategoryID: categoryID, Locale: locale, Name: name}
+		if err := tx.Create(&t).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CategoryService) saveCategory(category *models.Category, req dto.CategoryRequest) (dto.CategoryResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	slug := utils.Slugify(req.Slug)
+	if slug == "" {
+		slug = utils.Slugify(name)
+	}
+	if name == "" || slug == "" {
+		return dto.CategoryResponse{}, errors.New("category name is required")
+	}
+
+	var out dto.CategoryResponse
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.Category
+		if err := tx.Where("slug = ? AND id <> ?", slug, category.ID).First(&existing).Error; err == nil {
+			return ErrCategoryExists
+		}
+		if err := checkParent(tx, category.ID, req.ParentID); err != nil {
+			return err
+		}
+
+		category.Name = name
+		category.Slug = slug
+		category.ParentID = req.ParentID
+		category.DisplayOrder = req.DisplayOrder
+		if err := tx.Omit("Children", "Translations").Save(category).Error; err != nil {
+			return err
+		}
+		if err := setTranslations(tx, category.ID, req.Translations); err != nil {
+			return err
+		}
+
+		var err error
+		out, err = s.categoryResponse(tx, category.ID)
+		return err
+	})
+	return out, err
+}
+
+func (s *CategoryService) CreateCategory(req dto.CategoryRequest) (dto.CategoryResponse, error) {
+	return s.saveCategory(&models.Category{ID: uuid.New()}, req)
+}
+
+func (s *CategoryService) UpdateCategory(id string, req dto.CategoryRequest) (dto.CategoryResponse, error) {
+	var category models.Category
+	if err := s.DB.First(&category, "id = ?", id).Error; err != nil {
+		return dto.CategoryResponse{}, ErrCategoryNotFound
+	}
+	return s.saveCategory(&category, req)
+}
+
+// DeleteCategory removes a category. When moveTo is set its recipes and
+// subcategories are reassigned there first, which also serves to merge
+// duplicates such as "makanan" into "food"; otherwise a category that is
+// still in use is refused.
+func (s *CategoryService) DeleteCategory(id, moveTo string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.First(&category, "id = ?", id).Error; err != nil {
+			return ErrCategoryNotFound
+		}
+
+		if moveTo == "" {
+			var recipes, children int64
+			if err := tx.Unscoped().Model(&models.Recipe{}).Where("category_id = ?", category.ID).Count(&recipes).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children).Error; err != nil {
+				return err
+			}
+			if recipes > 0 || children > 0 {
+				return ErrCategoryInUse
+			}
+			return tx.Select("Translations").Delete(&category).Error
+		}
+
+		var target models.Category
+		if err := tx.First(&target, "id = ?", moveTo).Error; err != nil {
+			return errors.New("target category not found")
+		}
+		all, err := loadCategories(tx)
+		if err != nil {
+			return err
+		}
+		for _, d := range descendantIDs(all, category.ID) {
+			if d == target.ID {
+				return errors.New("cannot move a category into itself or its subcategories")
+			}
+		}
+
+		if err := tx.Unscoped().Model(&models.Recipe{}).Where("category_id = ?", category.ID).
+			Update("category_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID).
+			Update("parent_id", target.ID).Error; err != nil {
+			return err
+		}
+		return tx.Select("Translations").Delete(&category).Error
+	})
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSessionNotFound = errors.New("cooking session not found")
+	ErrSessionFinished = errors.New("cooking session has already finished")
+	ErrTimerNotFound   = errors.New("timer not found")
+)
+
+// maxTimerDuration caps a single timer at one day.
+const maxTimerDuration = 24 * time.Hour
+
+type CookingService struct {
+	DB *gorm.DB
+
+	mu       sync.Mutex
+	watchers map[uuid.UUID]map[chan struct{}]struct{}
+}
+
+func NewCookingService(db *gorm.DB) *CookingService {
+	return &CookingService{DB: db, watchers: map[uuid.UUID]map[chan struct{}]struct{}{}}
+}
+
+// Watch returns a channel that receives a value whenever the session changes
+// through this process. The returned function stops watching.
+func (s *CookingService) Watch(sessionID uuid.UUID) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	if s.watchers[sessionID] == nil {
+		s.watchers[sessionID] = map[chan struct{}]struct{}{}
+	}
+	s.watchers[sessionID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.watchers[sessionID], ch)
+		if len(s.watchers[sessionID]) == 0 {
+			delete(s.watchers, sessionID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *CookingService) notify(sessionID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.watchers[sessionID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// TimerStatus reports whether a timer is running, done or cancelled at now.
+func TimerStatus(t models.CookingTimer, now time.Time) string {
+	switch {
+	case t.CancelledAt != nil:
+		return dto.TimerStatusCancelled
+	case !t.EndsAt.After(now):
+		return dto.TimerStatusDone
+	default:
+		return dto.TimerStatusRunning
+	}
+}
+
+func toTimerResponse(t models.CookingTimer, now time.Time) dto.CookingTimerResponse {
+	res := dto.CookingTimerResponse{
+		ID:              t.ID,
+		StepID:          t.StepID,
+		Label:           t.Label,
+		DurationSeconds: t.DurationSeconds,
+		StartedAt:       t.CreatedAt,
+		EndsAt:          t.EndsAt,
+		Status:          TimerStatus(t, now),
+	}
+	if res.Status == dto.TimerStatusRunning {
+		res.RemainingSeconds = int(t.EndsAt.Sub(now).Round(time.Second) / time.Second)
+	}
+	return res
+}
+
+func (s *CookingService) toSessionResponse(session models.CookingSession) (dto.CookingSessionResponse, error) {
+	var recipe models.Recipe
+	if err := s.DB.Unscoped().Preload("Steps", orderedSteps).Preload("Steps.Images", orderedImages).First(&recipe, "id = ?", session.RecipeID).Error; err != nil {
+		return dto.CookingSessionResponse{}, ErrRecipeNotFound
+	}
+
+	now := time.Now()
+	res := dto.CookingSessionResponse{
+		ID:          session.ID,
+		RecipeID:    session.RecipeID,
+		RecipeTitle: recipe.Title,
+		CurrentStep: session.CurrentStep,
+		TotalSteps:  len(recipe.Steps),
+		Timers:      []dto.CookingTimerResponse{},
+		StartedAt:   session.CreatedAt,
+		FinishedAt:  session.FinishedAt,
+	}
+	steps := toStepResponses(recipe.Steps, privateMedia(recipe))
+	if i := session.CurrentStep - 1; i >= 0 && i < len(steps) {
+		res.Step = &steps[i]
+	}
+	for _, t := range session.Timers {
+		res.Timers = append(res.Timers, toTimerResponse(t, now))
+	}
+	return res, nil
+}
+
+func preloadTimers(db *gorm.DB) *gorm.DB {
+	return db.Preload("Timers", func(db *gorm.DB) *gorm.DB { return db.Order("cooking_timers.created_at ASC") })
+}
+
+// loadSession loads one of the user's cooking sessions with its timers.
+func (s *CookingService) loadSession(db *gorm.DB, userID, sessionID string) (models.CookingSession, error) {
+	var session models.CookingSession
+	if err := preloadTimers(db).First(&session, "id = ?", sessionID).Error; err != nil {
+		return session, ErrSessionNotFound
+	}
+	if session.UserID.String() != userID {
+		return session, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// activeSession loads a session that can still be changed.
+func (s *CookingService) activeSession(db *gorm.DB, userID, sessionID string) (models.CookingSession, error) {
+	session, err := s.loadSession(db, userID, sessionID)
+	if err != nil {
+		return session, err
+	}
+	if session.FinishedAt != nil {
+		return session, ErrSessionFinished
+	}
+	return session, nil
+}
+
+// StartSession starts cooking a recipe the user can see. When the user is
+// already cooking it, the running session is returned and created is false.
+func (s *CookingService) StartSession(userID, recipeID string) (res dto.CookingSessionResponse, created bool, err error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return res, false, errors.New("invalid user ID")
+	}
+
+	var recipe models.Recipe
+	if err := s.DB.First(&recipe, "id = ?", recipeID).Error; err != nil || !canView(recipe, userID) {
+		return res, false, ErrRecipeNotFound
+	}
+
+	running := func() (dto.CookingSessionResponse, error) {
+		var session models.CookingSession
+		if err := preloadTimers(s.DB).
+			Where("user_id = ? AND recipe_id = ? AND finished_at IS NULL", uid, recipe.ID).
+			First(&session).Error; err != nil {
+			return dto.CookingSessionResponse{}, err
+		}
+		return s.toSessionResponse(session)
+	}
+	res, err = running()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return res, false, err
+	}
+
+	// The unique index on active sessions keeps a concurrent start from
+	// adding a second one; the session it created is returned instead.
+	session := models.CookingSession{UserID: uid, RecipeID: recipe.ID, CurrentStep: 1}
+	result := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&session)
+	if result.Error != nil {
+		return res, false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		res, err = running()
+		return res, false, err
+	}
+	res, err = s.toSessionResponse(session)
+	return res, true, err
+}
+
+// ListSessions returns the user's unfinished cooking sessions, newest first.
+func (s *CookingService) ListSessions(userID string) ([]dto.CookingSessionResponse, error) {
+	var sessions []models.CookingSession
+	if err := preloadTimers(s.DB).
+		Where("user_id = ? AND finished_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]dto.CookingSessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		res, err := s.toSessionResponse(session)
+		if err != nil {
+			continue
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+func (s *CookingService) GetSession(userID, sessionID string) (dto.CookingSessionResponse, error) {
+	session, err := s.loadSession(s.DB, userID, sessionID)
+	if err != nil {
+		return dto.CookingSessionResponse{}, err
+	}
+	return s.toSessionResponse(session)
+}
+
+// SetStep moves the session to another step of the recipe.
+func (s *CookingService) SetStep(userID, sessionID string, step int) (dto.CookingSessionResponse, error) {
+	session, err := s.activeSession(s.DB, userID, sessionID)
+	if err != nil {
+		return dto.CookingSessionResponse{}, err
+	}
+
+	var total int64
+	if err := s.DB.Model(&models.Step{}).Where("recipe_id = ?", session.RecipeID).Count(&total).Error; err != nil {
+		return dto.CookingSessionResponse{}, err
+	}
+	if step < 1 || (total > 0 && int64(step) > total) {
+		return dto.CookingSessionResponse{}, fmt.Errorf("current_step must be between 1 and %d", max(total, 1))
+	}
+
+	if err := s.DB.Model(&session).Update("current_step", step).Error; err != nil {
+		return dto.CookingSessionResponse{}, err
+	}
+	s.notify(session.ID)
+	return s.toSessionResponse(session)
+}
+
+// FinishSession ends a cooking session and cancels its running timers.
+func (s *CookingService) FinishSession(userID, sessionID string) (dto.CookingSessionResponse, error) {
+	session, err := s.activeSession(s.DB, userID, sessionID)
+	if err != nil {
+		return dto.CookingSessionResponse{}, err
+	}
+
+	now := time.Now()
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CookingTimer{}).
+			Where("session_id = ? AND cancelled_at IS NULL AND ends_at > ?", session.ID, now).
+			Update("cancelled_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&session).Update("finished_at", now).Error
+	})
+	if err != nil {
+		return dto.CookingSessionResponse{}, err
+	}
+	s.notify(session.ID)
+
+	session, err = s.loadSession(s.DB, userID, sessionID)
+	if err != nil {
+		return dto.CookingSessionResponse{}, err
+	}
+	return s.toSessionResponse(session)
+}
+
+// StartTimer starts a countdown in the session. A timer for a step uses the
+// step's duration unless another one is given.
+func (s *CookingService) StartTimer(userID, sessionID string, req dto.StartTimerRequest) (dto.CookingTimerResponse, error) {
+	session, err := s.activeSession(s.DB, userID, sessionID)
+	if err != nil {
+		return dto.CookingTimerResponse{}, err
+	}
+
+	timer := models.CookingTimer{SessionID: session.ID, Label: req.Label, DurationSeconds: req.DurationSeconds}
+	if req.StepID != nil {
+		var step models.Step
+		if err := s.DB.First(&step, "id = ? AND recipe_id = ?", *req.StepID, session.RecipeID).Error; err != nil {
+			return dto.CookingTimerResponse{}, ErrStepNotFound
+		}
+		timer.StepID = &step.ID
+		if timer.Label == "" {
+			timer.Label = fmt.Sprintf("Step %d", step.Number)
+		}
+		if timer.DurationSeconds == 0 {
+			if seconds, _ := stepDuration(step); seconds != nil {
+				timer.DurationSeconds = *seconds
+			}
+		}
+	}
+
+	if timer.DurationSeconds <= 0 {
+		return dto.CookingTimerResponse{}, errors.New("duration_seconds is required for a step without a duration")
+	}
+	if time.Duration(timer.DurationSeconds)*time.Second > maxTimerDuration {
+		return dto.CookingTimerResponse{}, errors.New("timers cannot run longer than 24 hours")
+	}
+	if timer.Label == "" {
+		timer.Label = "Timer"
+	}
+
+	now := time.Now()
+	timer.CreatedAt = now
+	timer.EndsAt = now.Add(time.Duration(timer.DurationSeconds) * time.Second)
+	if err := s.DB.Create(&timer).Error; err != nil {
+		return dto.CookingTimerResponse{}, err
+	}
+	s.notify(session.ID)
+	return toTimerResponse(timer, now), nil
+}
+
+// CancelTimer stops a running timer. Cancelling a finished timer is a no-op.
+func (s *CookingService) CancelTimer(userID, sessionID, timerID string) (dto.CookingTimerResponse, error) {
+	session, err := s.loadSession(s.DB, userID, sessionID)
+	if err != nil {
+		return dto.CookingTimerResponse{}, err
+	}
+
+	var timer models.CookingTimer
+	if err := s.DB.First(&timer, "id = ? AND session_id = ?", timerID, session.ID).Error; err != nil {
+		return dto.CookingTimerResponse{}, ErrTimerNotFound
+	}
+
+	now := time.Now()
+	if TimerStatus(timer, now) == dto.TimerStatusRunning {
+		if err := s.DB.Model(&timer).Update("cancelled_at", now).Error; err != nil {
+			return dto.CookingTimerResponse{}, err
+		}
+		timer.CancelledAt = &now
+		s.notify(session.ID)
+	}
+	return toTimerResponse(timer, now), nil
+}
@@ -3,26 +3,25 @@ package services
 import (
 	"errors"
 	"fmt"
-	"io"
+	"math"
 	"mime/multipart"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/bayuTri-Code/BE-Recipe/database"
 	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
 	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type RecipeService struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	Nutrition *NutritionService
 }
 
 func NewRecipeService(db *gorm.DB) *RecipeService {
-	return &RecipeService{DB: db}
+	return &RecipeService{DB: db, Nutrition: NewNutritionService(db)}
 }
 
 func toUserSummary(u models.User) dto.UserSummaryResponse {
@@ -30,30 +29,96 @@ func toUserSummary(u models.User) dto.UserSummaryResponse {
 		ID:    u.ID,
 		Name:  u.Name,
 		Email: u.Email,
-		Avatar: u.Avatar,
+		Avatar: mediaURL(u.Avatar, false),
+
+		AvatarVariants:    mediaVariants(u.Avatar, false),
+		AvatarPlaceholder: toPlaceholder(u.AvatarPlaceholder),
 	}
 }
 
 func toIngredientResponses(items []models.Ingredient) []dto.IngredientResponse {
 	out := make([]dto.IngredientResponse, 0, len(items))
 	for _, it := range items {
-		out = append(out, dto.IngredientResponse{
-			ID:     it.ID,
-			Name:   it.Name,
-			Amount: it.Amount,
-		})
+		res := dto.IngredientResponse{
+			ID:         it.ID,
+			Name:       it.Name,
+			Amount:     it.Amount,
+			Quantity:   it.Quantity,
+			Unit:       it.Unit,
+			FoodID:     it.FoodID,
+			FoodManual: it.FoodManual,
+			Position:   it.Position,
+			Group:      it.Group,
+		}
+		if res.Quantity == nil {
+			if qty, unit, _, ok := utils.ParseAmount(it.Amount); ok {
+				res.Quantity = &qty
+				res.Unit = unit
+			}
+		}
+		out = append(out, res)
 	}
 	return out
 }
 
-func toStepResponses(items []models.Step) []dto.StepResponse {
+// newIngredient builds an ingredient row, filling the structured quantity
+// from the free-text amount when the client did not send one.
+func newIngredient(recipeID uuid.UUID, in dto.IngredientInput) models.Ingredient {
+	ing := models.Ingredient{
+		RecipeID: recipeID,
+		Name:     in.Name,
+		Amount:   in.Amount,
+		Quantity: in.Quantity,
+		Unit:     utils.NormalizeUnit(in.Unit),
+		FoodID:   in.FoodID,
+		Group:    strings.TrimSpace(in.Group),
+	}
+	if in.ID != nil {
+		ing.ID = *in.ID
+	}
+	if in.FoodID != nil {
+		ing.FoodManual = true
+	}
+	if ing.Quantity == nil {
+		if qty, unit, _, ok := utils.ParseAmount(in.Amount); ok {
+			ing.Quantity = &qty
+			ing.Unit = unit
+		}
+	}
+	return ing
+}
+
+// newStep builds a step row from its input and checks the author's timing.
+func newStep(recipeID uuid.UUID, in dto.StepInput) (models.Step, error) {
+	st := models.Step{
+		RecipeID: recipeID,
+		Number:   in.Number,
+		Detail:   in.Detail,
+		Section:  strings.TrimSpace(in.Section),
+	}
+	if in.ID != nil {
+		st.ID = *in.ID
+	}
+	var err error
+	st.DurationSeconds, st.Temperature, st.TemperatureUnit, err = stepTiming(in.DurationSeconds, in.Temperature, in.TemperatureUnit)
+	return st, err
+}
+
+func toStepResponses(items []models.Step, private bool) []dto.StepResponse {
 	out := make([]dto.StepResponse, 0, len(items))
 	for _, it := range items {
-		out = append(out, dto.StepResponse{
-			ID:     it.ID,
-			Number: it.Number,
-			Detail: it.Detail,
-		})
+		res := dto.StepResponse{
+			ID:      it.ID,
+			Number:  it.Number,
+			Detail:  it.Detail,
+			Section: it.Section,
+		}
+		res.DurationSeconds, res.DurationDetected = stepDuration(it)
+		res.Temperature, res.TemperatureUnit, res.TemperatureDetected = stepTemperature(it)
+		if len(it.Images) > 0 {
+			res.Images = toImageResponses(it.Images, private)
+		}
+		out = append(out, res)
 	}
 	return out
 }
@@ -69,85 +134,116 @@ func toFavoriteResponses(items []models.Favorite) []dto.FavoriteResponse {
 	return out
 }
 
-func toRecipeResponse(m models.Recipe) dto.RecipeResponse {
-	return dto.RecipeResponse{
-		ID:          m.ID,
-		Title:       m.Title,
-		Description: m.Description,
-		Category:    m.Category,
-		Thumbnail:   m.Thumbnail,
-		User:        toUserSummary(m.User),
-		Ingredients: toIngredientResponses(m.Ingredients),
-		Steps:       toStepResponses(m.Steps),
-		PrepTime:    m.PrepTime,
-		CookTime:    m.CookTime,
-		Servings:    m.Servings,
-		Favorites:   toFavoriteResponses(m.Favorites),
+// toRecipeResponse renders a recipe for viewerID, which is empty for
+// anonymous callers. A fork names its original only when the viewer may see
+// the original.
+func toRecipeResponse(m models.Recipe, viewerID string) dto.RecipeResponse {
+	var categoryName, categorySlug string
+	if m.Category != nil {
+		categoryName, categorySlug = m.Category.Name, m.Category.Slug
 	}
-}
 
-func (s *RecipeService) SaveThumbnail(file *multipart.FileHeader) (string, error) {
-	apiImagePath := os.Getenv("API_IMAGE_PATH")
-
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowedExts := map[string]bool{".jpg": true, ".jpeg": true, ".png": true}
-	if !allowedExts[ext] {
-		return "", errors.New("only jpg, jpeg, and png files are allowed")
-	}
-
-	if file.Size > 2*1024*1024 {
-		return "", errors.New("file size must not exceed 2MB")
-	}
-
-	filename := fmt.Sprintf("recipe_%d%s", time.Now().UnixNano(), ext)
-	storagePath := "public/storage"
-	fullPath := filepath.Join(storagePath, filename)
-
-	if err := os.MkdirAll(storagePath, os.ModePerm); err != nil {
-		return "", err
-	}
-
-	src, err := file.Open()
-	if err != nil {
-		return "", err
-	}
-	defer src.Close()
-
-	dst, err := os.Create(fullPath)
-	if err != nil {
-		return "", err
+	private := privateMedia(m)
+
+	var forkedFrom *dto.ForkAttribution
+	if m.ForkedFromID != nil {
+		forkedFrom = &dto.ForkAttribution{}
+		if o := m.ForkedFrom; o != nil && canView(*o, viewerID) {
+			forkedFrom = &dto.ForkAttribution{
+				RecipeID:   &o.ID,
+				Title:      o.Title,
+				AuthorID:   &o.UserID,
+				AuthorName: o.User.Name,
+			}
+		}
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, src); err != nil {
-		return "", err
+	return dto.RecipeResponse{
+		ID:           m.ID,
+		Title:        m.Title,
+		Description:  m.Description,
+		Category:     categoryName,
+		CategoryID:   m.CategoryID,
+		CategorySlug: categorySlug,
+		Thumbnail:    mediaURL(m.Thumbnail, private),
+		ThumbnailVariants: mediaVariants(m.Thumbnail, private),
+		ThumbnailPlaceholder: coverPlaceholder(m),
+		User:         toUserSummary(m.User),
+		Ingredients:  toIngredientResponses(m.Ingredients),
+		Steps:        toStepResponses(m.Steps, private),
+		Images:       toImageResponses(galleryImages(m.Images), private),
+		Videos:       toVideoResponses(m.Videos, private),
+
+		IngredientGroups: groupNames(len(m.Ingredients), func(i int) string { return m.Ingredients[i].Group }),
+		StepSections:     groupNames(len(m.Steps), func(i int) string { return m.Steps[i].Section }),
+		PrepTime:     m.PrepTime,
+		CookTime:     m.CookTime,
+		Servings:     m.Servings,
+		Favorites:    toFavoriteResponses(m.Favorites),
+		Nutrition:    toNutritionResponse(m),
+		Status:       m.Status,
+		PublishAt:    m.PublishAt,
+		PublishedAt:  m.PublishedAt,
+		Visibility:   m.Visibility,
+		ForkedFrom:   forkedFrom,
+		ForkCount:    m.ForkCount,
+		Version:      m.Version,
+
+		Allergens:      splitLabels(m.Allergens),
+		DietTags:       splitLabels(m.DietTags),
+		LabelOverrides: parseOverrides(m.LabelOverrides),
+		Tags:           toTagResponses(m.Tags),
 	}
+}
 
-	return fmt.Sprintf("%s/storage/%s", apiImagePath, filename), nil
+// preloadRecipe loads every relation rendered by toRecipeResponse.
+func preloadRecipe(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("User").
+		Preload("Category").
+		Preload("ForkedFrom.User").
+		Preload("Ingredients", orderedIngredients).
+		Preload("Steps", orderedSteps).
+		Preload("Steps.Images", orderedImages).
+		Preload("Images", orderedImages).
+		Preload("Videos", orderedVideos).
+		Preload("Favorites").
+		Preload("Tags", func(db *gorm.DB) *gorm.DB {
+			return db.Order("tags.name ASC")
+		})
 }
 
-func (s *RecipeService) DeleteThumbnail(thumbnailURL string) error {
-	if thumbnailURL == "" {
-		return nil
-	}
+// applyRecipeFilter narrows a recipe listing query by category, labels and
+// tags.
+func applyRecipeFilter(db *gorm.DB, filter dto.RecipeFilter) *gorm.DB {
+	return applyTagFilter(applyLabelFilter(applyCategoryFilter(db, filter), filter), filter)
+}
 
-	parts := strings.Split(thumbnailURL, "/storage/")
-	if len(parts) != 2 {
-		return nil
+// SaveImage stores an uploaded recipe image in every size and format and
+// returns the key of its largest JPEG and its placeholder.
+func (s *RecipeService) SaveImage(file *multipart.FileHeader) (string, models.ImagePlaceholder, error) {
+	if file.Size > 2*1024*1024 {
+		return "", models.ImagePlaceholder{}, errors.New("file size must not exceed 2MB")
 	}
+	return storeUpload(file)
+}
 
-	filename := parts[1]
-	fullPath := filepath.Join("public/storage", filename)
-
-	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
-		return err
-	}
+// DeleteImage drops a reference to a stored image, deleting it and its
+// variants once nothing refers to it.
+func (s *RecipeService) DeleteImage(key string) error {
+	return removeStored(key)
+}
 
-	return nil
+// CopyImage takes a new reference to a stored image and returns the key to
+// store. Images stored before uploads were content-addressed are duplicated.
+// A missing source image yields an empty key rather than an error.
+func (s *RecipeService) CopyImage(key string) (string, error) {
+	return copyStored(key, fmt.Sprintf("recipe_%d", time.Now().UnixNano()))
 }
 
 func (s *RecipeService) CreateRecipe(req dto.CreateRecipeRequest, userID uuid.UUID, thumbnail *multipart.FileHeader) (dto.RecipeResponse, error) {
 	var out dto.RecipeResponse
+	var files fileChanges
 
 	err := s.DB.Transaction(func(tx *gorm.DB) error {
 		var user models.User
@@ -155,78 +251,136 @@ func (s *RecipeService) CreateRecipe(req dto.CreateRecipeRequest, userID uuid.UU
 			return errors.New("user not found")
 		}
 
+		categoryID, err := resolveRecipeCategory(tx, req.Category)
+		if err != nil {
+			return err
+		}
+
 		recipe := models.Recipe{
 			ID:          uuid.New(),
 			Title:       req.Title,
 			Description: req.Description,
-			Category:    req.Category,
+			CategoryID:  categoryID,
 			PrepTime:    req.PrepTime,
 			CookTime:    req.CookTime,
 			Servings:    req.Servings,
 			UserID:      userID,
 		}
 
+		status := req.Status
+		if status == "" && req.PublishAt != nil {
+			status = models.RecipeStatusScheduled
+		} else if status == "" {
+			status = models.RecipeStatusPublished
+		}
+		if err := setRecipeStatus(&recipe, status, req.PublishAt); err != nil {
+			return err
+		}
+
+		recipe.Visibility = models.VisibilityPublic
+		if req.Visibility != "" {
+			if !IsValidVisibility(req.Visibility) {
+				return errors.New("visibility must be private, unlisted or public")
+			}
+			recipe.Visibility = req.Visibility
+		}
+
+		var placeholder models.ImagePlaceholder
 		if thumbnail != nil {
-			thumbnailURL, err := s.SaveThumbnail(thumbnail)
+			thumbnailKey, p, err := s.SaveImage(thumbnail)
 			if err != nil {
 				return fmt.Errorf("failed to save thumbnail: %w", err)
 			}
-			recipe.Thumbnail = thumbnailURL
+			files.add(thumbnailKey)
+			recipe.Thumbnail = thumbnailKey
+			placeholder = p
 		}
 
 		if err := tx.Create(&recipe).Error; err != nil {
 			return err
 		}
-
-		if len(req.Ingredients) > 0 {
-			ings := make([]models.Ingredient, 0, len(req.Ingredients))
-			for _, in := range req.Ingredients {
-				ings = append(ings, models.Ingredient{
-					ID:       uuid.New(),
-					RecipeID: recipe.ID,
-					Name:     in.Name,
-					Amount:   in.Amount,
-				})
+		if recipe.Thumbnail != "" {
+			cover := models.RecipeImage{RecipeID: recipe.ID, Key: recipe.Thumbnail, Position: 1, IsCover: true, Placeholder: placeholder}
+			if err := tx.Create(&cover).Error; err != nil {
+				return err
 			}
-			if err := tx.Create(&ings).Error; err != nil {
+		}
+
+		ings := make([]models.Ingredient, 0, len(req.Ingredients))
+		for _, in := range req.Ingredients {
+			ing := newIngredient(recipe.ID, in)
+			ing.ID = uuid.Nil
+			ings = append(ings, ing)
+		}
+		if err := syncIngredients(tx, recipe.ID, ings); err != nil {
+			return err
+		}
+
+		steps := make([]models.Step, 0, len(req.Steps))
+		for _, st := range req.Steps {
+			step, err := newStep(recipe.ID, st)
+			if err != nil {
 				return err
 			}
-			recipe.Ingredients = ings
+			step.ID = uuid.Nil
+			steps = append(steps, step)
+		}
+		if err := syncSteps(tx, recipe.ID, steps); err != nil {
+			return err
 		}
 
-		if len(req.Steps) > 0 {
-			steps := make([]models.Step, 0, len(req.Steps))
-			for _, st := range req.Steps {
-				steps = append(steps, models.Step{
-					ID:       uuid.New(),
-					RecipeID: recipe.ID,
-					Number:   st.Number,
-					Detail:   st.Detail,
-				})
+		if len(req.Tags) > 0 {
+			if err := setRecipeTags(tx, &recipe, req.Tags); err != nil {
+				return err
 			}
-			if err := tx.Create(&steps).Error; err != nil {
+		}
+
+		// Videos are processed in the background once the recipe exists.
+		for i, in := range req.Videos {
+			v, err := newVideo(tx, userID.String(), recipe.ID, in, i+1)
+			files.add(v.Key)
+			if err != nil {
 				return err
 			}
-			recipe.Steps = steps
+		}
+
+		if err := s.Nutrition.RecalculateRecipe(tx, recipe.ID); err != nil {
+			return err
+		}
+		if err := RecalculateLabels(tx, recipe.ID); err != nil {
+			return err
+		}
+		if err := recordRevision(tx, recipe.ID, userID); err != nil {
+			return err
+		}
+
+		if err := tx.
+			Preload("Category").
+			Preload("Ingredients", orderedIngredients).
+			Preload("Steps", orderedSteps).
+			Preload("Steps.Images", orderedImages).
+			Preload("Images", orderedImages).
+			Preload("Videos", orderedVideos).
+			Preload("Tags").
+			First(&recipe, "id = ?", recipe.ID).Error; err != nil {
+			return err
 		}
 
 		recipe.User = user
-		out = toRecipeResponse(recipe)
+		out = toRecipeResponse(recipe, userID.String())
 		return nil
 	})
+	files.finish(err)
+	if err == nil && len(req.Videos) > 0 {
+		notifyVideoJobs()
+	}
 
 	return out, err
 }
 
-func (s *RecipeService) GetAllRecipes() ([]dto.RecipeResponse, error) {
+func (s *RecipeService) GetAllRecipes(filter dto.RecipeFilter) ([]dto.RecipeResponse, error) {
 	var list []models.Recipe
-	err := s.DB.
-		Preload("User").
-		Preload("Ingredients").
-		Preload("Steps", func(db *gorm.DB) *gorm.DB {
-			return db.Order("steps.number ASC")
-		}).
-		Preload("Favorites").
+	err := preloadRecipe(applyRecipeFilter(publicOnly(s.DB), filter)).
 		Find(&list).Error
 	if err != nil {
 		return nil, err
@@ -234,43 +388,132 @@ func (s *RecipeService) GetAllRecipes() ([]dto.RecipeResponse, error) {
 
 	out := make([]dto.RecipeResponse, 0, len(list))
 	for _, r := range list {
-		out = append(out, toRecipeResponse(r))
+		out = append(out, toRecipeResponse(r, ""))
 	}
 	return out, nil
 }
 
-func (s *RecipeService) GetRecipeByID(id string) (dto.RecipeResponse, error) {
+// GetRecipeByID returns a recipe if the viewer may see it; unpublished,
+// private and unlisted recipes of other users are reported as not found.
+// viewerID is empty for anonymous callers.
+func (s *RecipeService) GetRecipeByID(id, viewerID string) (dto.RecipeResponse, error) {
 	var r models.Recipe
-	err := s.DB.
-		Preload("User").
-		Preload("Ingredients").
-		Preload("Steps", func(db *gorm.DB) *gorm.DB {
-			return db.Order("steps.number ASC")
-		}).
-		Preload("Favorites").
-		First(&r, "id = ?", id).Error
+	err := preloadRecipe(s.DB).First(&r, "id = ?", id).Error
 	if err != nil {
 		return dto.RecipeResponse{}, err
 	}
-	return toRecipeResponse(r), nil
+	if !canView(r, viewerID) {
+		return dto.RecipeResponse{}, ErrRecipeNotFound
+	}
+	return toRecipeResponse(r, viewerID), nil
 }
 
-func (s *RecipeService) UpdateRecipe(id string, req dto.UpdateRecipeRequest, thumbnail *multipart.FileHeader) (dto.RecipeResponse, error) {
+// ScaleRecipe returns a copy of the recipe with every ingredient amount
+// scaled from the recipe's own servings to the requested number.
+func (s *RecipeService) ScaleRecipe(res dto.RecipeResponse, servings int) (dto.RecipeResponse, error) {
+	if servings <= 0 {
+		return res, errors.New("servings must be greater than zero")
+	}
+	if res.Servings <= 0 {
+		return res, errors.New("recipe has no base servings to scale from")
+	}
+
+	factor := float64(servings) / float64(res.Servings)
+	scaled := make([]dto.IngredientResponse, 0, len(res.Ingredients))
+	for _, in := range res.Ingredients {
+		if in.Quantity != nil {
+			var qty float64
+			qty, in.Unit, in.Amount = utils.ScaleAmount(in.Amount, *in.Quantity, in.Unit, factor)
+			in.Quantity = &qty
+		}
+		scaled = append(scaled, in)
+	}
+
+	res.Ingredients = scaled
+	res.Servings = servings
+	return res, nil
+}
+
+// UnitSystemFor returns the saved measurement preference of a user, or an
+// empty string when the user cannot be found.
+func (s *RecipeService) UnitSystemFor(userID string) string {
+	var user models.User
+	if err := s.DB.Select("unit_system").First(&user, "id = ?", userID).Error; err != nil {
+		return ""
+	}
+	return user.UnitSystem
+}
+
+// LocalizeRecipe renders ingredient amounts and oven temperatures mentioned
+// in steps in the given measurement system.
+func (s *RecipeService) LocalizeRecipe(res dto.RecipeResponse, system string) dto.RecipeResponse {
+	if !utils.IsValidUnitSystem(system) {
+		return res
+	}
+
+	ingredients := make([]dto.IngredientResponse, 0, len(res.Ingredients))
+	for _, in := range res.Ingredients {
+		if in.Quantity != nil {
+			var qty float64
+			qty, in.Unit, in.Amount = utils.LocalizeAmount(in.Amount, *in.Quantity, in.Unit, in.Name, system)
+			in.Quantity = &qty
+		}
+		ingredients = append(ingredients, in)
+	}
+
+	steps := make([]dto.StepResponse, 0, len(res.Steps))
+	for _, st := range res.Steps {
+		st.Detail = utils.ConvertTemperaturesInText(st.Detail, system)
+		if st.Temperature != nil {
+			target := "C"
+			if system == utils.UnitSystemUS {
+				target = "F"
+			}
+			if st.TemperatureUnit != target {
+				t := math.Round(utils.ConvertTemperature(*st.Temperature, st.TemperatureUnit, target)/5) * 5
+				st.Temperature = &t
+				st.TemperatureUnit = target
+			}
+		}
+		steps = append(steps, st)
+	}
+
+	res.Ingredients = ingredients
+	res.Steps = steps
+	return res
+}
+
+// UpdateRecipe applies the author's changes and records the result as a new
+// revision. When expectedVersion is set the update is refused with
+// ErrVersionMismatch if the recipe changed in the meantime.
+func (s *RecipeService) UpdateRecipe(userID, id string, expectedVersion *int, req dto.UpdateRecipeRequest, thumbnail *multipart.FileHeader) (dto.RecipeResponse, error) {
 	var out dto.RecipeResponse
+	var files fileChanges
 	err := s.DB.Transaction(func(tx *gorm.DB) error {
-		var r models.Recipe
-		if err := tx.First(&r, "id = ?", id).Error; err != nil {
-			return errors.New("recipe not found")
+		r, err := lockOwnedRecipe(tx, userID, id, expectedVersion)
+		if err != nil {
+			return err
+		}
+		if err := ensureBaseRevision(tx, r); err != nil {
+			return err
 		}
 
 		if req.Title != nil {
+			if strings.TrimSpace(*req.Title) == "" {
+				return errors.New("title cannot be empty")
+			}
 			r.Title = *req.Title
 		}
 		if req.Description != nil {
 			r.Description = *req.Description
 		}
 		if req.Category != nil {
-			r.Category = *req.Category
+			categoryID, err := resolveRecipeCategory(tx, *req.Category)
+			if err != nil {
+				return err
+			}
+			r.CategoryID = categoryID
+			r.Category = nil
 		}
 		if req.PrepTime != nil {
 			r.PrepTime = *req.PrepTime
@@ -281,120 +524,147 @@ func (s *RecipeService) UpdateRecipe(id string, req dto.UpdateRecipeRequest, thu
 		if req.Servings != nil {
 			r.Servings = *req.Servings
 		}
-
-		if thumbnail != nil {
-			if err := s.DeleteThumbnail(r.Thumbnail); err != nil {
-				return fmt.Errorf("failed to delete old thumbnail: %w", err)
+		if req.Status != nil || req.PublishAt != nil {
+			status := ""
+			if req.Status != nil {
+				status = *req.Status
 			}
+			if err := setRecipeStatus(&r, status, req.PublishAt); err != nil {
+				return err
+			}
+		}
+		if req.Visibility != nil {
+			if !IsValidVisibility(*req.Visibility) {
+				return errors.New("visibility must be private, unlisted or public")
+			}
+			r.Visibility = *req.Visibility
+		}
 
-			thumbnailURL, err := s.SaveThumbnail(thumbnail)
+		if thumbnail != nil {
+			thumbnailKey, placeholder, err := s.SaveImage(thumbnail)
 			if err != nil {
 				return fmt.Errorf("failed to save new thumbnail: %w", err)
 			}
-			r.Thumbnail = thumbnailURL
+			files.add(thumbnailKey)
+			removed, err := replaceCover(tx, r.ID, thumbnailKey, placeholder)
+			if err != nil {
+				return err
+			}
+			files.replace(removed...)
+			r.Thumbnail = thumbnailKey
 		}
 
+		r.Version++
 		if err := tx.Save(&r).Error; err != nil {
 			return err
 		}
 
 		if req.Ingredients != nil {
-			if err := tx.Where("recipe_id = ?", r.ID).Delete(&models.Ingredient{}).Error; err != nil {
+			// Keep manual food overrides for ingredients that survive the edit.
+			var previous []models.Ingredient
+			if err := tx.Where("recipe_id = ? AND food_manual = ?", r.ID, true).Find(&previous).Error; err != nil {
 				return err
 			}
-			if len(req.Ingredients) > 0 {
-				ings := make([]models.Ingredient, 0, len(req.Ingredients))
-				for _, in := range req.Ingredients {
-					ings = append(ings, models.Ingredient{
-						ID:       uuid.New(),
-						RecipeID: r.ID,
-						Name:     in.Name,
-						Amount:   in.Amount,
-					})
+			overrides := make(map[string]*uuid.UUID, len(previous))
+			for _, p := range previous {
+				overrides[strings.ToLower(strings.TrimSpace(p.Name))] = p.FoodID
+			}
+
+			ings := make([]models.Ingredient, 0, len(req.Ingredients))
+			for _, in := range req.Ingredients {
+				ing := newIngredient(r.ID, in)
+				if foodID, ok := overrides[strings.ToLower(strings.TrimSpace(in.Name))]; ok && ing.FoodID == nil {
+					ing.FoodID = foodID
+					ing.FoodManual = true
 				}
-				if err := tx.Create(&ings).Error; err != nil {
+				ings = append(ings, ing)
+			}
+			if err := syncIngredients(tx, r.ID, ings); err != nil {
+				return err
+			}
+		}
+
+		if req.Steps != nil {
+			steps := make([]models.Step, 0, len(req.Steps))
+			for _, st := range req.Steps {
+				step, err := newStep(r.ID, st)
+				if err != nil {
 					return err
 				}
+				steps = append(steps, step)
+			}
+			if err := syncSteps(tx, r.ID, steps); err != nil {
+				return err
 			}
 		}
 
-		if req.Steps != nil {
-			if err := tx.Where("recipe_id = ?", r.ID).Delete(&models.Step{}).Error; err != nil {
+		if req.Tags != nil {
+			if err := setRecipeTags(tx, &r, req.Tags); err != nil {
 				return err
 			}
-			if len(req.Steps) > 0 {
-				steps := make([]models.Step, 0, len(req.Steps))
-				for _, st := range req.Steps {
-					steps = append(steps, models.Step{
-						ID:       uuid.New(),
-						RecipeID: r.ID,
-						Number:   st.Number,
-						Detail:   st.Detail,
-					})
-				}
-				if err := tx.Create(&steps).Error; err != nil {
-					return err
-				}
+		}
+
+		if req.Ingredients != nil || req.Servings != nil {
+			if err := s.Nutrition.RecalculateRecipe(tx, r.ID); err != nil {
+				return err
+			}
+			if err := RecalculateLabels(tx, r.ID); err != nil {
+				return err
 			}
 		}
 
-		if err := tx.
-			Preload("User").
-			Preload("Ingredients").
-			Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("steps.number ASC") }).
-			Preload("Favorites").
-			First(&r, "id = ?", r.ID).Error; err != nil {
+		if err := recordRevision(tx, r.ID, r.UserID); err != nil {
 			return err
 		}
 
-		out = toRecipeResponse(r)
+		if err := preloadRecipe(tx).First(&r, "id = ?", r.ID).Error; err != nil {
+			return err
+		}
+
+		out = toRecipeResponse(r, userID)
 		return nil
 	})
+	files.finish(err)
 	return out, err
 }
 
-func DeleteRecipeService(id string) error {
-	var recipe models.Recipe
-
-	if err := database.Db.First(&recipe, "id = ?", id).Error; err != nil {
-		return err
-	}
-
-	if recipe.Thumbnail != "" {
-		parts := strings.Split(recipe.Thumbnail, "/storage/")
-		if len(parts) == 2 {
-			filename := parts[1]
-			fullPath := filepath.Join("public/storage", filename)
-			os.Remove(fullPath)
+// DeleteRecipe moves one of the user's recipes to the trash. The recipe and
+// its files are kept until the retention period ends, so it can be restored.
+func (s *RecipeService) DeleteRecipe(userID, id string, expectedVersion *int) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		recipe, err := lockOwnedRecipe(tx, userID, id, expectedVersion)
+		if err != nil {
+			return err
 		}
-	}
 
-	if err := database.Db.Unscoped().Delete(&recipe).Error; err != nil {
-		return err
-	}
+		if recipe.ForkedFromID != nil {
+			if err := tx.Model(&models.Recipe{}).Where("id = ? AND fork_count > 0", *recipe.ForkedFromID).
+				UpdateColumn("fork_count", gorm.Expr("fork_count - 1")).Error; err != nil {
+				return err
+			}
+		}
 
-	return nil
+		return tx.Delete(&recipe).Error
+	})
 }
 
-func (s *RecipeService) GetRecipesByUserID(userID string) ([]dto.RecipeResponse, error) {
+// GetRecipesByUserID lists the user's own recipes in every status, or only
+// in the given status when one is passed.
+func (s *RecipeService) GetRecipesByUserID(userID, status string) ([]dto.RecipeResponse, error) {
 	var recipes []models.Recipe
 
-	err := s.DB.
-		Preload("User").
-		Preload("Ingredients").
-		Preload("Steps", func(db *gorm.DB) *gorm.DB {
-			return db.Order("steps.number ASC")
-		}).
-		Preload("Favorites").
-		Where("user_id = ?", userID).
-		Find(&recipes).Error
+	query := preloadRecipe(s.DB).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&recipes).Error
 	if err != nil {
 		return nil, err
 	}
 
 	out := make([]dto.RecipeResponse, 0, len(recipes))
 	for _, r := range recipes {
-		out = append(out, toRecipeResponse(r))
+		out = append(out, toRecipeResponse(r, userID))
 	}
 
 	return out, nil
@@ -0,0 +1,8 @@
+package services
+
+import "errors"
+
+var (
+	ErrRecipeNotFound = errors.New("recipe not found")
+	ErrForbidden      = errors.New("you are not allowed to modify this recipe")
+)
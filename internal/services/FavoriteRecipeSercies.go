@@ -3,6 +3,7 @@ package services
 import (
 	"fmt"
 
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
 	"github.com/bayuTri-Code/BE-Recipe/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -15,30 +16,56 @@ type FavoriteService struct {
 func NewFavoriteService(db *gorm.DB) *FavoriteService {
 	return &FavoriteService{DB: db}
 }
-func (s *FavoriteService) GetAllFavorites(userID string) ([]models.Favorite, error) {
-	var favorites []models.Favorite
-
+// GetAllFavorites lists the user's favorites, each with its recipe rendered
+// like every other recipe response.
+func (s *FavoriteService) GetAllFavorites(userID string) ([]dto.FavoriteRecipeResponse, error) {
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID")
 	}
 
+	var favorites []models.Favorite
 	err = s.DB.
-		Preload("Recipe", func(db *gorm.DB) *gorm.DB {
-			return db.
-				Preload("User").
-				Preload("Ingredients").
-				Preload("Steps", func(db *gorm.DB) *gorm.DB {
-					return db.Order("steps.number ASC")
-				})
-		}).
-		Where("user_id = ?", userUUID).
+		Joins("JOIN recipes ON recipes.id = favorites.recipe_id AND recipes.deleted_at IS NULL").
+		Where("favorites.user_id = ?", userUUID).
+		// Hide favorites whose recipe another author unpublished or made private.
+		Where("(recipes.status = ? AND recipes.visibility = ?) OR recipes.user_id = ?",
+			models.RecipeStatusPublished, models.VisibilityPublic, userUUID).
 		Find(&favorites).Error
-
 	if err != nil {
 		return nil, err
 	}
-	return favorites, nil
+	if len(favorites) == 0 {
+		return []dto.FavoriteRecipeResponse{}, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(favorites))
+	for _, f := range favorites {
+		ids = append(ids, f.RecipeID)
+	}
+	var recipes []models.Recipe
+	if err := preloadRecipe(s.DB).Find(&recipes, "id IN ?", ids).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Recipe, len(recipes))
+	for _, r := range recipes {
+		byID[r.ID] = r
+	}
+
+	out := make([]dto.FavoriteRecipeResponse, 0, len(favorites))
+	for _, f := range favorites {
+		r, ok := byID[f.RecipeID]
+		if !ok {
+			continue
+		}
+		out = append(out, dto.FavoriteRecipeResponse{
+			ID:       f.ID,
+			UserID:   f.UserID,
+			RecipeID: f.RecipeID,
+			Recipe:   toRecipeResponse(r, userID),
+		})
+	}
+	return out, nil
 }
 
 func (s *FavoriteService) AddFavoriteService(userID, recipeID string) (bool, error) {
@@ -52,6 +79,11 @@ func (s *FavoriteService) AddFavoriteService(userID, recipeID string) (bool, err
 		return false, fmt.Errorf("invalid user ID")
 	}
 
+	var recipe models.Recipe
+	if err := s.DB.Select("id, user_id, status, visibility").First(&recipe, "id = ?", recipeUUID).Error; err != nil || !canView(recipe, userID) {
+		return false, ErrRecipeNotFound
+	}
+
 	var fav models.Favorite
 	err = s.DB.Where("user_id = ? AND recipe_id = ?", userUUID, recipeUUID).First(&fav).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+)
+
+// newPlaceholder turns a computed placeholder into the columns it is kept in.
+func newPlaceholder(p utils.ImagePlaceholder) models.ImagePlaceholder {
+	return models.ImagePlaceholder{BlurHash: p.BlurHash, Color: p.Color}
+}
+
+// toPlaceholder renders the placeholder of an image, or nil when it has
+// none.
+func toPlaceholder(p models.ImagePlaceholder) *dto.ImagePlaceholder {
+	if p.BlurHash == "" {
+		return nil
+	}
+	return &dto.ImagePlaceholder{BlurHash: p.BlurHash, Color: p.Color}
+}
+
+// coverPlaceholder returns the placeholder of a recipe's thumbnail, which is
+// the image marked as its cover.
+func coverPlaceholder(r models.Recipe) *dto.ImagePlaceholder {
+	for _, it := range r.Images {
+		if it.IsCover && it.Key == r.Thumbnail {
+			return toPlaceholder(it.Placeholder)
+		}
+	}
+	return nil
+}
+
+// PlaceholderReport is the outcome of a placeholder backfill.
+type PlaceholderReport struct {
+	Checked int
+	Created int
+	Failed  int
+}
+
+// placeholderColumns lists the rows that show stored images: the table, the
+// column holding the key and the prefix of its placeholder columns.
+var placeholderColumns = []struct {
+	table, key, prefix string
+}{
+	{"recipe_images", "key", ""},
+	{"recipe_videos", "poster_key", "poster_"},
+	{"users", "avatar", "avatar_"},
+	{"users", "banner", "banner_"},
+	{"uploads", "key", ""},
+}
+
+// BackfillPlaceholders computes the placeholders of stored images that were
+// processed before placeholders existed and fills them in on the rows that
+// show them. Images that cannot be read are counted as failed and skipped.
+func (s *MediaService) BackfillPlaceholders() (PlaceholderReport, error) {
+	var report PlaceholderReport
+	// An image shared by several rows is only decoded once.
+	computed := map[string]*models.ImagePlaceholder{}
+
+	for _, c := range placeholderColumns {
+		query := s.DB.Table(c.table).
+			Where(c.key+" <> '' AND "+c.key+" NOT LIKE ?", "%://%").
+			Where(c.prefix + "blur_hash = ''")
+		if c.table == "uploads" {
+			query = query.Where("kind = ?", models.UploadKindImage)
+		}
+		var keys []string
+		if err := query.Distinct().Pluck(c.key, &keys).Error; err != nil {
+			return report, err
+		}
+
+		for _, key := range keys {
+			p, ok := computed[key]
+			if !ok {
+				report.Checked++
+				p = decodeStoredPlaceholder(key)
+				computed[key] = p
+				if p == nil {
+					report.Failed++
+				} else {
+					report.Created++
+				}
+			}
+			if p == nil {
+				continue
+			}
+			err := s.DB.Table(c.table).
+				Where(c.key+" = ? AND "+c.prefix+"blur_hash = ''", key).
+				Updates(map[string]interface{}{c.prefix + "blur_hash": p.BlurHash, c.prefix + "color": p.Color}).Error
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// decodeStoredPlaceholder computes the placeholder of a stored image, or
+// returns nil when it cannot be read.
+func decodeStoredPlaceholder(key string) *models.ImagePlaceholder {
+	f, err := storage.Default.Open(context.Background(), key)
+	if err != nil {
+		log.Printf("Placeholder backfill: cannot open %s: %v", key, err)
+		return nil
+	}
+	defer f.Close()
+	p, err := utils.DecodePlaceholder(f)
+	if err != nil {
+		log.Printf("Placeholder backfill: %s: %v", key, err)
+		return nil
+	}
+	placeholder := newPlaceholder(p)
+	return &placeholder
+}
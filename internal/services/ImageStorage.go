@@ -0,0 +1,357 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bayuTri-Code/BE-Recipe/database"
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Key prefixes of the stored images. Uploads are stored under
+// contentPrefix, named after their content; the other prefixes hold images
+// uploaded before that, in the directories they were kept in before storage
+// was pluggable.
+const (
+	contentPrefix     = "content"
+	recipeImagePrefix = "storage"
+	avatarPrefix      = "profile_storage"
+	bannerPrefix      = "profile_banner"
+)
+
+var imageContentTypes = map[string]string{"jpg": "image/jpeg", "webp": "image/webp"}
+
+// contentStem returns the stem a content-addressed key shares with its
+// variants; a file without variants is its key without the extension.
+// Older keys report ok=false.
+func contentStem(key string) (stem string, ok bool) {
+	if !strings.HasPrefix(key, contentPrefix+"/") {
+		return "", false
+	}
+	if stem, ok := utils.ImageStem(key); ok {
+		return stem, true
+	}
+	return strings.TrimSuffix(key, path.Ext(key)), true
+}
+
+// withFileLock runs fn in a transaction holding a lock on the stored image
+// stem, so taking and dropping references to it cannot interleave with its
+// files being written or deleted.
+func withFileLock(stem string, fn func(tx *gorm.DB) error) error {
+	return database.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", stem).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// storeContent takes a reference on the content-addressed files under stem.
+// write stores them when they are not stored yet.
+func storeContent(stem string, size int64, write func(ctx context.Context) error) error {
+	return withFileLock(stem, func(tx *gorm.DB) error {
+		res := tx.Model(&models.StoredFile{}).Where("stem = ?", stem).
+			Update("ref_count", gorm.Expr("ref_count + 1"))
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+		if err := write(context.Background()); err != nil {
+			return err
+		}
+		return tx.Create(&models.StoredFile{Stem: stem, Size: size, RefCount: 1}).Error
+	})
+}
+
+// storeUpload stores an image uploaded in a form with storeImage.
+func storeUpload(file *multipart.FileHeader) (string, models.ImagePlaceholder, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", models.ImagePlaceholder{}, err
+	}
+	defer src.Close()
+	return storeImage(src)
+}
+
+// storeImage runs an image through the image pipeline and stores it under a
+// key derived from the SHA-256 of the processed files, so the same image is
+// only stored once. It takes a reference on the image and returns the key
+// of its largest JPEG, which is the key the image is known by, with the
+// placeholder the rows showing the image keep.
+func storeImage(r io.Reader) (string, models.ImagePlaceholder, error) {
+	variants, placeholder, err := utils.ProcessImage(r)
+	if err != nil {
+		return "", models.ImagePlaceholder{}, err
+	}
+
+	sum := sha256.New()
+	var size int64
+	for _, v := range variants {
+		sum.Write(v.Data)
+		size += int64(len(v.Data))
+	}
+	stem := path.Join(contentPrefix, hex.EncodeToString(sum.Sum(nil)))
+
+	key := ""
+	for _, v := range variants {
+		if v.Format == "jpg" {
+			key = utils.VariantName(stem, v.Width, v.Format)
+		}
+	}
+
+	err = storeContent(stem, size, func(ctx context.Context) error {
+		var written []string
+		for _, v := range variants {
+			variantKey := utils.VariantName(stem, v.Width, v.Format)
+			if err := storage.Default.Put(ctx, variantKey, bytes.NewReader(v.Data), int64(len(v.Data)), imageContentTypes[v.Format]); err != nil {
+				for _, w := range written {
+					_ = storage.Default.Delete(ctx, w)
+				}
+				return err
+			}
+			written = append(written, variantKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", models.ImagePlaceholder{}, err
+	}
+	return key, newPlaceholder(placeholder), nil
+}
+
+// storeFile stores a file that is kept as uploaded, such as a video, under
+// the SHA-256 of its bytes and takes a reference on it. open is called once
+// to hash the file and once more if it has to be written.
+func storeFile(open func() (io.ReadCloser, error), size int64, ext, contentType string) (string, error) {
+	src, err := open()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	_, err = io.Copy(sum, src)
+	src.Close()
+	if err != nil {
+		return "", err
+	}
+	stem := path.Join(contentPrefix, hex.EncodeToString(sum.Sum(nil)))
+	key := stem + "." + ext
+
+	err = storeContent(stem, size, func(ctx context.Context) error {
+		src, err := open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return storage.Default.Put(ctx, key, src, size, contentType)
+	})
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// deleteFiles deletes a stored image and all of its variants.
+func deleteFiles(key string) error {
+	var errs []error
+	for _, k := range utils.ImageFiles(key) {
+		if err := storage.Default.Delete(context.Background(), k); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeStored drops a reference to a stored image. A content-addressed
+// image is deleted with its last reference; older images have a single
+// owner and are deleted right away.
+func removeStored(key string) error {
+	if key == "" {
+		return nil
+	}
+	stem, ok := contentStem(key)
+	if !ok {
+		return deleteFiles(key)
+	}
+	return withFileLock(stem, func(tx *gorm.DB) error {
+		var f models.StoredFile
+		if err := tx.First(&f, "stem = ?", stem).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if f.RefCount > 1 {
+			return tx.Model(&f).Update("ref_count", f.RefCount-1).Error
+		}
+		if err := tx.Delete(&f).Error; err != nil {
+			return err
+		}
+		return deleteFiles(key)
+	})
+}
+
+// copyStored gives a new owner its own reference to a stored image and
+// returns the key to store. Content-addressed images are shared; older
+// images are duplicated under a new stem in the same prefix. A missing image
+// yields "".
+func copyStored(key, stem string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+	if shared, ok := contentStem(key); ok {
+		found := false
+		err := withFileLock(shared, func(tx *gorm.DB) error {
+			res := tx.Model(&models.StoredFile{}).Where("stem = ?", shared).
+				Update("ref_count", gorm.Expr("ref_count + 1"))
+			found = res.RowsAffected > 0
+			return res.Error
+		})
+		if err != nil || !found {
+			return "", err
+		}
+		return key, nil
+	}
+
+	// Variants keep their "_w<width>.<ext>" suffix; older single files keep
+	// their extension.
+	files := utils.ImageFiles(key)
+	renamed := func(k string) string {
+		dir, name := path.Split(k)
+		if i := strings.LastIndex(name, "_w"); i >= 0 && len(files) > 1 {
+			return dir + stem + name[i:]
+		}
+		return dir + stem + strings.ToLower(path.Ext(name))
+	}
+
+	ctx := context.Background()
+	if err := storage.Default.Copy(ctx, key, renamed(key)); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	copies := []string{renamed(key)}
+	for _, k := range files {
+		if k == key {
+			continue
+		}
+		err := storage.Default.Copy(ctx, k, renamed(k))
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			for _, c := range copies {
+				_ = storage.Default.Delete(ctx, c)
+			}
+			return "", err
+		}
+		copies = append(copies, renamed(k))
+	}
+	return renamed(key), nil
+}
+
+// fileChanges keeps stored files in step with a database change: files
+// stored for it are released when it fails, and files it replaces are only
+// released once it has committed. Files a crash leaves behind are picked up
+// by the orphan collector.
+type fileChanges struct {
+	added    []string
+	replaced []string
+}
+
+func (f *fileChanges) add(keys ...string) {
+	f.added = appendKeys(f.added, keys)
+}
+
+func (f *fileChanges) replace(keys ...string) {
+	f.replaced = appendKeys(f.replaced, keys)
+}
+
+func appendKeys(list, keys []string) []string {
+	for _, k := range keys {
+		if k != "" {
+			list = append(list, k)
+		}
+	}
+	return list
+}
+
+// finish releases the files the change left unused; err is the outcome of
+// the change.
+func (f *fileChanges) finish(err error) {
+	keys := f.replaced
+	if err != nil {
+		keys = f.added
+	}
+	for _, k := range keys {
+		if err := removeStored(k); err != nil {
+			log.Printf("Failed to remove stored file %s: %v", k, err)
+		}
+	}
+}
+
+// privateMedia reports whether a recipe's images need signed links: only
+// published public recipes can be fetched with a plain link.
+func privateMedia(r models.Recipe) bool {
+	return !canView(r, "")
+}
+
+// mediaURL turns a stored key into the link sent to clients. Private media
+// gets a signed, expiring link to the media handler. Values that already
+// are links are passed through.
+func mediaURL(key string, private bool) string {
+	if key == "" || strings.Contains(key, "://") {
+		return key
+	}
+	if private {
+		return signedMediaURL(key)
+	}
+	return storage.Default.URL(key)
+}
+
+// signedMediaURL links to a file that is not public. Storage that presigns
+// links, such as a private S3 bucket, serves the file itself; otherwise the
+// link goes to the media handler with a signature it checks.
+func signedMediaURL(key string) string {
+	expires := utils.MediaExpiry(time.Now())
+	if p, ok := storage.Default.(storage.Presigner); ok {
+		// Signing at the start of the TTL window keeps the link the same,
+		// and cacheable, until the window moves on.
+		window := 2 * utils.MediaURLTTL()
+		link, err := p.PresignGet(key, expires.Add(-window), window)
+		if err != nil {
+			log.Printf("Failed to presign %s: %v", key, err)
+			return ""
+		}
+		return link
+	}
+	return fmt.Sprintf("%s%s/%s?expires=%d&signature=%s",
+		strings.TrimRight(os.Getenv("API_IMAGE_PATH"), "/"), storage.MediaPath, key,
+		expires.Unix(), utils.SignMedia(key, expires))
+}
+
+func mediaVariants(key string, private bool) dto.ImageVariants {
+	if strings.Contains(key, "://") {
+		return nil
+	}
+	url := storage.Default.URL
+	if private {
+		url = signedMediaURL
+	}
+	return utils.ImageSrcsets(key, url)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMediaNotFound  = errors.New("file not found")
+	ErrMediaForbidden = errors.New("media link is invalid or has expired")
+)
+
+type MediaService struct {
+	DB *gorm.DB
+}
+
+func NewMediaService(db *gorm.DB) *MediaService {
+	return &MediaService{DB: db}
+}
+
+// Open returns a stored file for a media request. A signed request is
+// allowed while its signature is valid. Without a signature, files of
+// recipes that are not public, or that were deleted, are reported missing
+// so their names cannot be probed.
+func (s *MediaService) Open(key, expires, signature string) (io.ReadCloser, error) {
+	key, err := storage.CleanKey(key)
+	if err != nil {
+		return nil, ErrMediaNotFound
+	}
+
+	if signature != "" || expires != "" {
+		exp, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || !utils.VerifyMedia(key, exp, signature, time.Now()) {
+			return nil, ErrMediaForbidden
+		}
+	} else {
+		private, err := s.privateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if private {
+			return nil, ErrMediaNotFound
+		}
+	}
+
+	f, err := storage.Default.Open(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrMediaNotFound
+	}
+	return f, err
+}
+
+// recipeMedia lists the tables holding recipe files and their key columns.
+var recipeMedia = []struct {
+	table   string
+	columns []string
+}{
+	{"recipe_images", []string{"recipe_images.key"}},
+	{"recipe_videos", []string{"recipe_videos.key", "recipe_videos.poster_key"}},
+}
+
+// countRecipeMedia counts the recipe images and videos that use key and
+// whose recipe matches the condition.
+func (s *MediaService) countRecipeMedia(key string, recipeCond string, args ...any) (int64, error) {
+	var total int64
+	for _, m := range recipeMedia {
+		var n int64
+		if err := s.DB.Table(m.table).
+			Joins("JOIN recipes ON recipes.id = "+m.table+".recipe_id").
+			Where(recipeCond, args...).
+			Where(keyMatch(key, m.columns...)).
+			Count(&n).Error; err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// privateKey reports whether key, or the image it is a variant of, belongs
+// to a recipe only signed links may show. A file shared with a public
+// recipe or a profile stays public.
+func (s *MediaService) privateKey(key string) (bool, error) {
+	private, err := s.countRecipeMedia(key,
+		"(recipes.deleted_at IS NOT NULL OR recipes.status <> ? OR recipes.visibility <> ?)",
+		models.RecipeStatusPublished, models.VisibilityPublic)
+	if err != nil || private == 0 {
+		return false, err
+	}
+
+	public, err := s.countRecipeMedia(key,
+		"recipes.deleted_at IS NULL AND recipes.status = ? AND recipes.visibility = ?",
+		models.RecipeStatusPublished, models.VisibilityPublic)
+	if err != nil || public > 0 {
+		return false, err
+	}
+	err = s.DB.Model(&models.User{}).Where(keyMatch(key, "avatar", "banner")).Count(&public).Error
+	return public == 0, err
+}
+
+// keyMatch builds a condition matching rows whose columns hold key or, for
+// a variant, the image it belongs to.
+func keyMatch(key string, columns ...string) clause.Expr {
+	stem, ok := utils.ImageStem(key)
+	var conds []string
+	var args []any
+	for _, col := range columns {
+		conds = append(conds, col+" = ?")
+		args = append(args, key)
+		if ok {
+			conds = append(conds, col+` LIKE ? ESCAPE '\'`)
+			args = append(args, escapeLike(stem)+`\_w%`)
+		}
+	}
+	return gorm.Expr("("+strings.Join(conds, " OR ")+")", args...)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
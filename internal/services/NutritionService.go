@@ -0,0 +1,431 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bayuTri-Code/BE-Recipe/database"
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NutritionService struct {
+	DB *gorm.DB
+}
+
+func NewNutritionService(db *gorm.DB) *NutritionService {
+	return &NutritionService{DB: db}
+}
+
+var nutrientColumns = []string{
+	"food_id", "description", "aliases", "piece_g", "energy_kcal", "protein_g", "fat_g",
+	"carbohydrate_g", "fiber_g", "sugars_g", "sodium_mg", "calcium_mg", "iron_mg",
+	"potassium_mg", "vitamin_c_mg",
+}
+
+// ImportFoods reads a nutrient CSV (values per 100 g) and upserts every row
+// into the foods table keyed by its source id. It returns the number of rows
+// imported.
+func (s *NutritionService) ImportFoods(r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, col := range nutrientColumns {
+		if _, ok := index[col]; !ok {
+			return 0, fmt.Errorf("missing column %q", col)
+		}
+	}
+
+	optional := func(row []string, col string) string {
+		if i, ok := index[col]; ok && i < len(row) {
+			return strings.ToLower(strings.TrimSpace(row[i]))
+		}
+		return ""
+	}
+
+	var foods []models.Food
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		num := func(col string) (float64, error) {
+			v := strings.TrimSpace(row[index[col]])
+			if v == "" {
+				return 0, nil
+			}
+			return strconv.ParseFloat(v, 64)
+		}
+
+		sourceID, err := strconv.Atoi(strings.TrimSpace(row[index["food_id"]]))
+		if err != nil {
+			return 0, fmt.Errorf("line %d: invalid food_id", line)
+		}
+
+		values := make(map[string]float64, len(nutrientColumns))
+		for _, col := range nutrientColumns[3:] {
+			v, err := num(col)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: invalid %s", line, col)
+			}
+			values[col] = v
+		}
+
+		foods = append(foods, models.Food{
+			ID:          uuid.New(),
+			SourceID:    sourceID,
+			Description: strings.TrimSpace(row[index["description"]]),
+			Aliases:     strings.ToLower(strings.TrimSpace(row[index["aliases"]])),
+			PieceGrams:  values["piece_g"],
+			Allergens:   optional(row, "allergens"),
+			DietFlags:   optional(row, "diet_flags"),
+			NutritionFacts: models.NutritionFacts{
+				Calories:      values["energy_kcal"],
+				ProteinG:      values["protein_g"],
+				FatG:          values["fat_g"],
+				CarbohydrateG: values["carbohydrate_g"],
+				FiberG:        values["fiber_g"],
+				SugarsG:       values["sugars_g"],
+				SodiumMg:      values["sodium_mg"],
+				CalciumMg:     values["calcium_mg"],
+				IronMg:        values["iron_mg"],
+				PotassiumMg:   values["potassium_mg"],
+				VitaminCMg:    values["vitamin_c_mg"],
+			},
+		})
+	}
+
+	if len(foods) == 0 {
+		return 0, nil
+	}
+
+	updates := []string{
+		"description", "aliases", "piece_grams", "calories", "protein_g", "fat_g",
+		"carbohydrate_g", "fiber_g", "sugars_g", "sodium_mg", "calcium_mg", "iron_mg",
+		"potassium_mg", "vitamin_c_mg", "updated_at",
+	}
+	// Labels are only replaced by a file that has them, so importing a
+	// nutrients-only file keeps the labels already on the foods.
+	for _, col := range []string{"allergens", "diet_flags"} {
+		if _, ok := index[col]; ok {
+			updates = append(updates, col)
+		}
+	}
+
+	err = s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source_id"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}).CreateInBatches(&foods, 100).Error
+	if err != nil {
+		return 0, err
+	}
+	return len(foods), nil
+}
+
+// SeedFoods imports the bundled dataset when the foods table is empty.
+func (s *NutritionService) SeedFoods() error {
+	var count int64
+	if err := s.DB.Model(&models.Food{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := s.ImportFoods(bytes.NewReader(database.NutrientsCSV))
+	return err
+}
+
+func toFoodResponse(f models.Food) dto.FoodResponse {
+	return dto.FoodResponse{
+		ID:          f.ID,
+		SourceID:    f.SourceID,
+		Description: f.Description,
+		Aliases:     strings.Split(f.Aliases, "|"),
+		PieceGrams:  f.PieceGrams,
+	}
+}
+
+// SearchFoods lists dataset foods whose description or aliases contain q.
+func (s *NutritionService) SearchFoods(q string) ([]dto.FoodResponse, error) {
+	var foods []models.Food
+	query := s.DB.Order("description ASC").Limit(50)
+	if q = strings.TrimSpace(q); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(description) LIKE ? OR aliases LIKE ?", like, like)
+	}
+	if err := query.Find(&foods).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]dto.FoodResponse, 0, len(foods))
+	for _, f := range foods {
+		out = append(out, toFoodResponse(f))
+	}
+	return out, nil
+}
+
+var nameTokenizer = regexp.MustCompile(`[^\p{L}\p{N}']+`)
+
+func tokenize(s string) []string {
+	var out []string
+	for _, t := range nameTokenizer.Split(strings.ToLower(s), -1) {
+		if t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// matchFood picks the food whose longest alias appears as whole words in
+// the ingredient name, e.g. "bawang putih cincang" matches "bawang putih".
+func matchFood(foods []models.Food, name string) *models.Food {
+	words := " " + strings.Join(tokenize(name), " ") + " "
+
+	var best *models.Food
+	bestScore := 0
+	for i := range foods {
+		for _, alias := range strings.Split(foods[i].Aliases, "|") {
+			tokens := tokenize(alias)
+			if len(tokens) == 0 || !strings.Contains(words, " "+strings.Join(tokens, " ")+" ") {
+				continue
+			}
+			score := len(tokens)*100 + len(alias)
+			if score > bestScore {
+				best, bestScore = &foods[i], score
+			}
+		}
+	}
+	return best
+}
+
+// ingredientGrams estimates the weight of an ingredient from its structured
+// quantity: masses convert directly, volumes go through the ingredient
+// density (water when unknown) and counts use the food's piece weight.
+func ingredientGrams(ing models.Ingredient, food *models.Food) (float64, bool) {
+	qty, unit := 0.0, ing.Unit
+	if ing.Quantity != nil {
+		qty = *ing.Quantity
+	} else if parsed, parsedUnit, _, ok := utils.ParseAmount(ing.Amount); ok {
+		qty, unit = parsed, parsedUnit
+	} else {
+		return 0, false
+	}
+
+	switch utils.UnitKind(unit) {
+	case utils.KindMass:
+		g, err := utils.ConvertUnit(qty, unit, "g")
+		return g, err == nil
+	case utils.KindVolume:
+		ml, err := utils.ConvertUnit(qty, unit, "ml")
+		if err != nil {
+			return 0, false
+		}
+		density, ok := utils.DensityFor(ing.Name)
+		if !ok {
+			density = 1
+		}
+		return ml * density, true
+	default:
+		if food.PieceGrams <= 0 {
+			return 0, false
+		}
+		return qty * food.PieceGrams, true
+	}
+}
+
+func addFacts(total *models.NutritionFacts, f models.NutritionFacts, factor float64) {
+	total.Calories += f.Calories * factor
+	total.ProteinG += f.ProteinG * factor
+	total.FatG += f.FatG * factor
+	total.CarbohydrateG += f.CarbohydrateG * factor
+	total.FiberG += f.FiberG * factor
+	total.SugarsG += f.SugarsG * factor
+	total.SodiumMg += f.SodiumMg * factor
+	total.CalciumMg += f.CalciumMg * factor
+	total.IronMg += f.IronMg * factor
+	total.PotassiumMg += f.PotassiumMg * factor
+	total.VitaminCMg += f.VitaminCMg * factor
+}
+
+// RecalculateRecipe matches the recipe's ingredients against the dataset,
+// computes nutrition per serving and caches it on the recipe row.
+func (s *NutritionService) RecalculateRecipe(tx *gorm.DB, recipeID uuid.UUID) error {
+	var recipe models.Recipe
+	if err := tx.Preload("Ingredients").First(&recipe, "id = ?", recipeID).Error; err != nil {
+		return ErrRecipeNotFound
+	}
+
+	var foods []models.Food
+	if err := tx.Find(&foods).Error; err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]*models.Food, len(foods))
+	for i := range foods {
+		byID[foods[i].ID] = &foods[i]
+	}
+
+	var total models.NutritionFacts
+	complete := true
+	for _, ing := range recipe.Ingredients {
+		var food *models.Food
+		if ing.FoodManual && ing.FoodID != nil {
+			food = byID[*ing.FoodID]
+		} else {
+			food = matchFood(foods, ing.Name)
+			var matchedID *uuid.UUID
+			if food != nil {
+				matchedID = &food.ID
+			}
+			if !sameFood(ing.FoodID, matchedID) {
+				if err := tx.Model(&models.Ingredient{}).Where("id = ?", ing.ID).
+					UpdateColumn("food_id", matchedID).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if food == nil {
+			complete = false
+			continue
+		}
+		grams, ok := ingredientGrams(ing, food)
+		if !ok {
+			complete = false
+			continue
+		}
+		addFacts(&total, food.NutritionFacts, grams/100)
+	}
+
+	servings := recipe.Servings
+	if servings <= 0 {
+		servings = 1
+	}
+	var perServing models.NutritionFacts
+	addFacts(&perServing, total, 1/float64(servings))
+
+	now := time.Now()
+	return tx.Model(&models.Recipe{}).Where("id = ?", recipe.ID).UpdateColumns(map[string]interface{}{
+		"nutrition_calories":       perServing.Calories,
+		"nutrition_protein_g":      perServing.ProteinG,
+		"nutrition_fat_g":          perServing.FatG,
+		"nutrition_carbohydrate_g": perServing.CarbohydrateG,
+		"nutrition_fiber_g":        perServing.FiberG,
+		"nutrition_sugars_g":       perServing.SugarsG,
+		"nutrition_sodium_mg":      perServing.SodiumMg,
+		"nutrition_calcium_mg":     perServing.CalciumMg,
+		"nutrition_iron_mg":        perServing.IronMg,
+		"nutrition_potassium_mg":   perServing.PotassiumMg,
+		"nutrition_vitamin_c_mg":   perServing.VitaminCMg,
+		"nutrition_complete":       complete,
+		"nutrition_updated_at":     now,
+	}).Error
+}
+
+func sameFood(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// SetIngredientFood overrides the food an ingredient is mapped to. Passing a
+// nil foodID drops the override and goes back to auto-matching.
+func (s *NutritionService) SetIngredientFood(userID, recipeID, ingredientID string, foodID *uuid.UUID) (dto.RecipeResponse, error) {
+	var out dto.RecipeResponse
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var recipe models.Recipe
+		if err := tx.First(&recipe, "id = ?", recipeID).Error; err != nil {
+			return ErrRecipeNotFound
+		}
+		if recipe.UserID.String() != userID {
+			return ErrForbidden
+		}
+
+		var ing models.Ingredient
+		if err := tx.First(&ing, "id = ? AND recipe_id = ?", ingredientID, recipe.ID).Error; err != nil {
+			return ErrIngredientNotFound
+		}
+
+		if foodID != nil {
+			if err := tx.First(&models.Food{}, "id = ?", *foodID).Error; err != nil {
+				return errors.New("food not found")
+			}
+		}
+
+		if err := tx.Model(&ing).UpdateColumns(map[string]interface{}{
+			"food_id":     foodID,
+			"food_manual": foodID != nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := s.RecalculateRecipe(tx, recipe.ID); err != nil {
+			return err
+		}
+		if err := RecalculateLabels(tx, recipe.ID); err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, recipe.ID); err != nil {
+			return err
+		}
+
+		if err := preloadRecipe(tx).First(&recipe, "id = ?", recipe.ID).Error; err != nil {
+			return err
+		}
+
+		out = toRecipeResponse(recipe, userID)
+		return nil
+	})
+	return out, err
+}
+
+func roundNutrient(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+func toNutritionResponse(r models.Recipe) *dto.NutritionResponse {
+	if r.NutritionUpdatedAt == nil {
+		return nil
+	}
+	n := r.Nutrition
+	return &dto.NutritionResponse{
+		Calories:      roundNutrient(n.Calories),
+		ProteinG:      roundNutrient(n.ProteinG),
+		FatG:          roundNutrient(n.FatG),
+		CarbohydrateG: roundNutrient(n.CarbohydrateG),
+		FiberG:        roundNutrient(n.FiberG),
+		SugarsG:       roundNutrient(n.SugarsG),
+		SodiumMg:      roundNutrient(n.SodiumMg),
+		CalciumMg:     roundNutrient(n.CalciumMg),
+		IronMg:        roundNutrient(n.IronMg),
+		PotassiumMg:   roundNutrient(n.PotassiumMg),
+		VitaminCMg:    roundNutrient(n.VitaminCMg),
+		PerServing:    true,
+		Complete:      r.NutritionComplete,
+		UpdatedAt:     r.NutritionUpdatedAt,
+	}
+}
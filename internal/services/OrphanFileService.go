@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"gorm.io/gorm"
+)
+
+const defaultOrphanGraceHours = 24
+
+// OrphanGracePeriod is how old an unreferenced file must be before it is
+// deleted, configured in hours with ORPHAN_GRACE_HOURS. Younger files may
+// belong to an upload whose transaction has not committed yet.
+func OrphanGracePeriod() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("ORPHAN_GRACE_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = defaultOrphanGraceHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// OrphanReport is the outcome of one run of the orphan collector.
+type OrphanReport struct {
+	Scanned int
+	Orphans []storage.Object
+	Deleted int
+}
+
+// fileReferences holds every stored key the database refers to, and the
+// stems of those keys so that all variants of an image count as used. counts
+// tells how many rows refer to each content-addressed stem, and uploads
+// holds the IDs of the uploads whose chunks are still needed.
+type fileReferences struct {
+	keys    map[string]bool
+	stems   map[string]bool
+	counts  map[string]int
+	uploads map[string]bool
+}
+
+func (r fileReferences) has(key string) bool {
+	if rest, ok := strings.CutPrefix(key, uploadChunkPrefix+"/"); ok {
+		id, _, _ := strings.Cut(rest, "/")
+		return r.uploads[id]
+	}
+	if r.keys[key] {
+		return true
+	}
+	stem, ok := utils.ImageStem(key)
+	return ok && r.stems[stem]
+}
+
+// fileReferenceSources lists the columns that hold stored keys. A thumbnail
+// repeats the key of its recipe's cover image, so it does not hold a
+// reference of its own.
+var fileReferenceSources = []struct {
+	model   any
+	column  string
+	counted bool
+}{
+	{&models.RecipeImage{}, "key", true},
+	{&models.Recipe{}, "thumbnail", false},
+	{&models.User{}, "avatar", true},
+	{&models.User{}, "banner", true},
+	{&models.RecipeVideo{}, "key", true},
+	{&models.RecipeVideo{}, "poster_key", true},
+	{&models.Upload{}, "key", true},
+}
+
+// countReferences counts the rows that hold a reference to the
+// content-addressed files under stem.
+func countReferences(tx *gorm.DB, stem string) (int, error) {
+	total := 0
+	for _, src := range fileReferenceSources {
+		if !src.counted {
+			continue
+		}
+		var n int64
+		if err := tx.Unscoped().Model(src.model).Where(src.column+" LIKE ?", stem+"%").
+			Count(&n).Error; err != nil {
+			return 0, err
+		}
+		total += int(n)
+	}
+	return total, nil
+}
+
+// loadFileReferences collects the keys of recipe images, thumbnails, avatars
+// and banners. Trashed recipes and deleted users keep their files until they
+// are purged.
+func loadFileReferences(db *gorm.DB) (fileReferences, error) {
+	refs := fileReferences{keys: map[string]bool{}, stems: map[string]bool{}, counts: map[string]int{}, uploads: map[string]bool{}}
+	for _, src := range fileReferenceSources {
+		var keys []string
+		if err := db.Unscoped().Model(src.model).Where(src.column+" <> ''").
+			Pluck(src.column, &keys).Error; err != nil {
+			return refs, err
+		}
+		for _, k := range keys {
+			refs.keys[k] = true
+			if stem, ok := utils.ImageStem(k); ok {
+				refs.stems[stem] = true
+			}
+			if stem, ok := contentStem(k); ok && src.counted {
+				refs.counts[stem]++
+			}
+		}
+	}
+
+	var uploads []string
+	if err := db.Model(&models.Upload{}).Pluck("id", &uploads).Error; err != nil {
+		return refs, err
+	}
+	for _, id := range uploads {
+		refs.uploads[id] = true
+	}
+	return refs, nil
+}
+
+// CollectOrphans finds stored files no row refers to and, unless dryRun is
+// set, deletes the ones older than grace. It also corrects the reference
+// counts of content-addressed files that have not changed within grace.
+func (s *MediaService) CollectOrphans(grace time.Duration, dryRun bool) (OrphanReport, error) {
+	var report OrphanReport
+	ctx := context.Background()
+	cutoff := time.Now().Add(-grace)
+
+	// List before loading references: a file uploaded in between is newer
+	// than the cutoff and is left alone.
+	var candidates []storage.Object
+	for _, prefix := range []string{contentPrefix, recipeImagePrefix, avatarPrefix, bannerPrefix, uploadChunkPrefix} {
+		err := storage.Default.List(ctx, prefix+"/", func(o storage.Object) error {
+			report.Scanned++
+			if o.ModTime.Before(cutoff) {
+				candidates = append(candidates, o)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	refs, err := loadFileReferences(s.DB)
+	if err != nil {
+		return report, err
+	}
+	for _, o := range candidates {
+		if refs.has(o.Key) {
+			continue
+		}
+		report.Orphans = append(report.Orphans, o)
+		if dryRun {
+			continue
+		}
+		deleted, err := deleteOrphan(o.Key, cutoff)
+		if err != nil {
+			return report, err
+		}
+		if deleted {
+			report.Deleted++
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+	return report, reconcileRefCounts(s.DB, refs, cutoff)
+}
+
+// deleteOrphan deletes an unreferenced file. A content-addressed file is
+// kept while its reference count changed after cutoff, as an upload of the
+// same image may not have committed yet.
+func deleteOrphan(key string, cutoff time.Time) (bool, error) {
+	stem, ok := contentStem(key)
+	if !ok {
+		return true, storage.Default.Delete(context.Background(), key)
+	}
+	deleted := false
+	err := withFileLock(stem, func(tx *gorm.DB) error {
+		var f models.StoredFile
+		err := tx.First(&f, "stem = ?", stem).Error
+		if err == nil && f.UpdatedAt.After(cutoff) {
+			return nil
+		}
+		if err == nil {
+			if err := tx.Delete(&f).Error; err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		deleted = true
+		return storage.Default.Delete(context.Background(), key)
+	})
+	return deleted, err
+}
+
+// reconcileRefCounts sets the reference counts that have not changed since
+// cutoff to the number of rows referring to each file. The rows are counted
+// again under the file's lock, as a reference dropped after refs was loaded
+// may be released in the meantime; a count that changed, or rows that no
+// longer match refs, are left for the next run.
+func reconcileRefCounts(db *gorm.DB, refs fileReferences, cutoff time.Time) error {
+	var files []models.StoredFile
+	if err := db.Where("updated_at < ?", cutoff).Find(&files).Error; err != nil {
+		return err
+	}
+	for _, f := range files {
+		if refs.counts[f.Stem] == f.RefCount {
+			continue
+		}
+		err := withFileLock(f.Stem, func(tx *gorm.DB) error {
+			var current models.StoredFile
+			if err := tx.First(&current, "stem = ?", f.Stem).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil
+				}
+				return err
+			}
+			if !current.UpdatedAt.Before(cutoff) {
+				return nil
+			}
+			count, err := countReferences(tx, f.Stem)
+			if err != nil || count != refs.counts[f.Stem] || count == current.RefCount {
+				return err
+			}
+			if count == 0 {
+				// The files themselves are collected as orphans.
+				err = tx.Delete(&current).Error
+			} else {
+				err = tx.Model(&current).Update("ref_count", count).Error
+			}
+			if err == nil {
+				log.Printf("Corrected reference count of %s from %d to %d", f.Stem, current.RefCount, count)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartOrphanCollector runs CollectOrphans every interval until the
+// returned stop function is called.
+func StartOrphanCollector(db *gorm.DB, interval time.Duration) (stop func()) {
+	service := NewMediaService(db)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				report, err := service.CollectOrphans(OrphanGracePeriod(), false)
+				if err != nil {
+					log.Printf("Failed to collect orphaned files: %v", err)
+				} else if report.Deleted > 0 {
+					log.Printf("Deleted %d orphaned files", report.Deleted)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ForkRecipe copies a recipe the user can see into their own account,
+// including ingredients, steps, tags and copies of its image files. The fork starts
+// as a private draft so it can be adapted before it is published.
+func (s *RecipeService) ForkRecipe(userID, recipeID string) (dto.RecipeResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return dto.RecipeResponse{}, errors.New("invalid user ID")
+	}
+
+	var out dto.RecipeResponse
+	var files fileChanges
+	pendingVideos := false
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var original models.Recipe
+		if err := tx.
+			Preload("Ingredients").
+			Preload("Steps").
+			Preload("Images").
+			Preload("Videos").
+			Preload("Tags").
+			First(&original, "id = ?", recipeID).Error; err != nil {
+			return ErrRecipeNotFound
+		}
+		if !canView(original, userID) {
+			return ErrRecipeNotFound
+		}
+		if original.UserID == uid {
+			return errors.New("you cannot fork your own recipe")
+		}
+
+		fork := models.Recipe{
+			ID:                 uuid.New(),
+			Title:              original.Title,
+			Description:        original.Description,
+			CategoryID:         original.CategoryID,
+			PrepTime:           original.PrepTime,
+			CookTime:           original.CookTime,
+			Servings:           original.Servings,
+			UserID:             uid,
+			Status:             models.RecipeStatusDraft,
+			Visibility:         models.VisibilityPrivate,
+			ForkedFromID:       &original.ID,
+			Nutrition:          original.Nutrition,
+			NutritionComplete:  original.NutritionComplete,
+			NutritionUpdatedAt: original.NutritionUpdatedAt,
+			Allergens:          original.Allergens,
+			DietTags:           original.DietTags,
+			LabelOverrides:     original.LabelOverrides,
+		}
+
+		if err := tx.Omit("Ingredients", "Steps", "Tags", "Images", "Videos").Create(&fork).Error; err != nil {
+			return err
+		}
+
+		if len(original.Ingredients) > 0 {
+			ings := make([]models.Ingredient, 0, len(original.Ingredients))
+			for _, in := range original.Ingredients {
+				in.ID = uuid.New()
+				in.RecipeID = fork.ID
+				ings = append(ings, in)
+			}
+			if err := tx.Create(&ings).Error; err != nil {
+				return err
+			}
+		}
+
+		stepIDs := make(map[uuid.UUID]uuid.UUID, len(original.Steps))
+		if len(original.Steps) > 0 {
+			steps := make([]models.Step, 0, len(original.Steps))
+			for _, st := range original.Steps {
+				id := uuid.New()
+				stepIDs[st.ID] = id
+				st.ID = id
+				st.RecipeID = fork.ID
+				steps = append(steps, st)
+			}
+			if err := tx.Create(&steps).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, img := range original.Images {
+			key, err := s.CopyImage(img.Key)
+			if err != nil {
+				return fmt.Errorf("failed to copy image: %w", err)
+			}
+			if key == "" {
+				continue
+			}
+			files.add(key)
+
+			img.ID = uuid.New()
+			img.RecipeID = fork.ID
+			img.Key = key
+			if img.StepID != nil {
+				stepID := stepIDs[*img.StepID]
+				img.StepID = &stepID
+			}
+			if err := tx.Create(&img).Error; err != nil {
+				return err
+			}
+			if img.IsCover {
+				if err := tx.Model(&fork).UpdateColumn("thumbnail", key).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// Videos keep their metadata; ones still waiting are processed again
+		// for the fork.
+		for _, v := range original.Videos {
+			var err error
+			if v.Key, err = s.CopyImage(v.Key); err != nil {
+				return fmt.Errorf("failed to copy video: %w", err)
+			}
+			files.add(v.Key)
+			if v.PosterKey, err = s.CopyImage(v.PosterKey); err != nil {
+				return fmt.Errorf("failed to copy video poster: %w", err)
+			}
+			files.add(v.PosterKey)
+			if v.ExternalURL == "" && v.Key == "" {
+				continue
+			}
+
+			v.ID = uuid.New()
+			v.RecipeID = fork.ID
+			if v.Status != models.VideoStatusReady {
+				v.Status = models.VideoStatusPending
+				v.Attempts = 0
+				v.NextAttemptAt = nil
+				pendingVideos = true
+			}
+			if err := tx.Create(&v).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(original.Tags) > 0 {
+			if err := tx.Model(&fork).Association("Tags").Append(original.Tags); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&original).UpdateColumn("fork_count", gorm.Expr("fork_count + 1")).Error; err != nil {
+			return err
+		}
+		if err := recordRevision(tx, fork.ID, uid); err != nil {
+			return err
+		}
+
+		if err := preloadRecipe(tx).First(&fork, "id = ?", fork.ID).Error; err != nil {
+			return err
+		}
+		out = toRecipeResponse(fork, userID)
+		return nil
+	})
+	files.finish(err)
+	if err == nil && pendingVideos {
+		notifyVideoJobs()
+	}
+	return out, err
+}
+
+// ListForks returns the public forks of a recipe the caller can see.
+func (s *RecipeService) ListForks(recipeID, viewerID string) ([]dto.RecipeResponse, error) {
+	var original models.Recipe
+	if err := s.DB.First(&original, "id = ?", recipeID).Error; err != nil || !canView(original, viewerID) {
+		return nil, ErrRecipeNotFound
+	}
+
+	var forks []models.Recipe
+	if err := preloadRecipe(publicOnly(s.DB)).
+		Where("forked_from_id = ?", original.ID).
+		Order("created_at DESC").
+		Find(&forks).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]dto.RecipeResponse, 0, len(forks))
+	for _, r := range forks {
+		out = append(out, toRecipeResponse(r, viewerID))
+	}
+	return out, nil
+}
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"errors"
+	"mime/multipart"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImageNotFound = errors.New("image not found")
+	ErrCoverInStep   = errors.New("only a gallery image can be the cover")
+)
+
+func orderedImages(db *gorm.DB) *gorm.DB {
+	return db.Order("recipe_images.position ASC")
+}
+
+// toImageResponses renders images; private selects signed links for images
+// of recipes that are not public.
+func toImageResponses(items []models.RecipeImage, private bool) []dto.RecipeImageResponse {
+	out := make([]dto.RecipeImageResponse, 0, len(items))
+	for _, it := range items {
+		out = append(out, dto.RecipeImageResponse{
+			ID:       it.ID,
+			StepID:   it.StepID,
+			URL:      mediaURL(it.Key, private),
+			Variants: mediaVariants(it.Key, private),
+			AltText:  it.AltText,
+			Position: it.Position,
+			IsCover:  it.IsCover,
+
+			Placeholder: toPlaceholder(it.Placeholder),
+		})
+	}
+	return out
+}
+
+// galleryImages keeps the images that are not attached to a step.
+func galleryImages(items []models.RecipeImage) []models.RecipeImage {
+	out := make([]models.RecipeImage, 0, len(items))
+	for _, it := range items {
+		if it.StepID == nil {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// imageOrder returns the IDs of the recipe's gallery images, or of the
+// images of one step, in order.
+func imageOrder(tx *gorm.DB, recipeID uuid.UUID, stepID *uuid.UUID) ([]uuid.UUID, error) {
+	query := orderedImages(tx.Model(&models.RecipeImage{})).Where("recipe_id = ?", recipeID)
+	if stepID == nil {
+		query = query.Where("step_id IS NULL")
+	} else {
+		query = query.Where("step_id = ?", *stepID)
+	}
+	var ids []uuid.UUID
+	err := query.Pluck("id", &ids).Error
+	return ids, err
+}
+
+// setCover makes the image the recipe's cover and mirrors its key into
+// Recipe.Thumbnail. A nil image leaves the recipe without a cover.
+func setCover(tx *gorm.DB, recipeID uuid.UUID, image *models.RecipeImage) error {
+	if err := tx.Model(&models.RecipeImage{}).Where("recipe_id = ? AND is_cover = ?", recipeID, true).
+		Update("is_cover", false).Error; err != nil {
+		return err
+	}
+	thumbnail := ""
+	if image != nil {
+		if err := tx.Model(image).Update("is_cover", true).Error; err != nil {
+			return err
+		}
+		thumbnail = image.Key
+	}
+	return tx.Model(&models.Recipe{}).Where("id = ?", recipeID).UpdateColumn("thumbnail", thumbnail).Error
+}
+
+// firstGalleryImage returns the first gallery image of the recipe, or nil
+// when the gallery is empty.
+func firstGalleryImage(tx *gorm.DB, recipeID uuid.UUID) (*models.RecipeImage, error) {
+	var first models.RecipeImage
+	err := orderedImages(tx).Where("recipe_id = ? AND step_id IS NULL", recipeID).First(&first).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &first, nil
+}
+
+// replaceCover deletes the current cover image and puts a new image with the
+// given key and placeholder first in the gallery as the cover. An empty key
+// only removes the cover. It returns the keys of images that can be deleted
+// once the transaction commits.
+func replaceCover(tx *gorm.DB, recipeID uuid.UUID, key string, placeholder models.ImagePlaceholder) ([]string, error) {
+	var removed []string
+	var current models.RecipeImage
+	err := tx.Where("recipe_id = ? AND is_cover = ?", recipeID, true).First(&current).Error
+	if err == nil {
+		if err := tx.Delete(&current).Error; err != nil {
+			return nil, err
+		}
+		removed = append(removed, current.Key)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if key == "" {
+		return removed, setCover(tx, recipeID, nil)
+	}
+
+	image := models.RecipeImage{ID: uuid.New(), RecipeID: recipeID, Key: key, Placeholder: placeholder}
+	if err := tx.Create(&image).Error; err != nil {
+		return nil, err
+	}
+	ids, err := imageOrder(tx, recipeID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeOrder(tx, &models.RecipeImage{}, "position", moveTo(ids, image.ID, 1), nil); err != nil {
+		return nil, err
+	}
+	return removed, setCover(tx, recipeID, &image)
+}
+
+// editMedia runs a change to the images of one of the user's recipes. Media
+// is not part of revisions, so only the version is bumped. Images whose keys
+// fn returns are deleted after the change is committed.
+func (s *RecipeService) editMedia(userID, recipeID string, expectedVersion *int, fn func(tx *gorm.DB, r models.Recipe) ([]string, error)) (dto.RecipeResponse, error) {
+	var out dto.RecipeResponse
+	var files fileChanges
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		r, err := lockOwnedRecipe(tx, userID, recipeID, expectedVersion)
+		if err != nil {
+			return err
+		}
+		removed, err := fn(tx, r)
+		if err != nil {
+			return err
+		}
+		files.replace(removed...)
+		if err := bumpVersion(tx, r.ID); err != nil {
+			return err
+		}
+
+		if err := preloadRecipe(tx).First(&r, "id = ?", r.ID).Error; err != nil {
+			return err
+		}
+		out = toRecipeResponse(r, userID)
+		return nil
+	})
+	files.finish(err)
+	return out, err
+}
+
+// stepOfRecipe resolves a step reference for an image. An empty reference
+// means the gallery.
+func stepOfRecipe(tx *gorm.DB, recipeID uuid.UUID, stepRef string) (*uuid.UUID, error) {
+	if stepRef == "" {
+		return nil, nil
+	}
+	var st models.Step
+	if err := tx.First(&st, "id = ? AND recipe_id = ?", stepRef, recipeID).Error; err != nil {
+		return nil, ErrStepNotFound
+	}
+	return &st.ID, nil
+}
+
+// ListImages returns the gallery and step images of a recipe the viewer may
+// see, gallery first.
+func (s *RecipeService) ListImages(recipeID, viewerID string) ([]dto.RecipeImageResponse, error) {
+	r, err := s.viewableRecipe(recipeID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	var images []models.RecipeImage
+	if err := s.DB.Where("recipe_id = ?", r.ID).
+		Order("step_id IS NOT NULL, step_id, position ASC").
+		Find(&images).Error; err != nil {
+		return nil, err
+	}
+	return toImageResponses(images, privateMedia(r)), nil
+}
+
+// AddImage adds an image to the gallery, or to a step when stepRef is set.
+// The image is either uploaded as file or taken from a finished resumable
+// upload. The first gallery image becomes the cover unless another cover is
+// already chosen.
+func (s *RecipeService) AddImage(userID, recipeID string, expectedVersion *int, file *multipart.FileHeader, uploadID, altText, stepRef string, cover bool) (dto.RecipeResponse, error) {
+	var key string
+	var placeholder models.ImagePlaceholder
+	var err error
+	if uploadID != "" {
+		key, placeholder, err = claimUpload(s.DB, userID, uploadID, models.UploadKindImage)
+	} else {
+		key, placeholder, err = s.SaveImage(file)
+	}
+	if err != nil {
+		return dto.RecipeResponse{}, err
+	}
+
+	res, err := s.editMedia(userID, recipeID, expectedVersion, func(tx *gorm.DB, r models.Recipe) ([]string, error) {
+		stepID, err := stepOfRecipe(tx, r.ID, stepRef)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := imageOrder(tx, r.ID, stepID)
+		if err != nil {
+			return nil, err
+		}
+
+		image := models.RecipeImage{
+			ID:       uuid.New(),
+			RecipeID: r.ID,
+			StepID:   stepID,
+			Key:      key,
+			AltText:  altText,
+			Position: len(ids) + 1,
+
+			Placeholder: placeholder,
+		}
+		if err := tx.Create(&image).Error; err != nil {
+			return nil, err
+		}
+
+		if cover || (stepID == nil && r.Thumbnail == "") {
+			return nil, setCover(tx, r.ID, &image)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		_ = s.DeleteImage(key)
+	}
+	return res, err
+}
+
+// UpdateImage changes the alt text of an image or moves it between the
+// gallery and the steps. A moved image goes to the end of its new place; a
+// cover moved into a step hands the cover to the first gallery image.
+func (s *RecipeService) UpdateImage(userID, recipeID, imageID string, expectedVersion *int, req dto.UpdateImageRequest) (dto.RecipeResponse, error) {
+	return s.editMedia(userID, recipeID, expectedVersion, func(tx *gorm.DB, r models.Recipe) ([]string, error) {
+		var image models.RecipeImage
+		if err := tx.First(&image, "id = ? AND recipe_id = ?", imageID, r.ID).Error; err != nil {
+			return nil, ErrImageNotFound
+		}
+
+		if req.AltText != nil {
+			image.AltText = *req.AltText
+		}
+		if req.StepID == nil {
+			return nil, tx.Save(&image).Error
+		}
+
+		stepID, err := stepOfRecipe(tx, r.ID, *req.StepID)
+		if err != nil {
+			return nil, err
+		}
+		previous := image.StepID
+		ids, err := imageOrder(tx, r.ID, stepID)
+		if err != nil {
+			return nil, err
+		}
+		image.StepID = stepID
+		image.Position = len(ids) + 1
+		if err := tx.Save(&image).Error; err != nil {
+			return nil, err
+		}
+
+		left, err := imageOrder(tx, r.ID, previous)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeOrder(tx, &models.RecipeImage{}, "position", left, nil); err != nil {
+			return nil, err
+		}
+
+		if image.IsCover && stepID != nil {
+			next, err := firstGalleryImage(tx, r.ID)
+			if err != nil {
+				return nil, err
+			}
+			return nil, setCover(tx, r.ID, next)
+		}
+		return nil, nil
+	})
+}
+
+// RemoveImage deletes an image and its file. When it was the cover, the
+// first remaining gallery image takes its place.
+func (s *RecipeService) RemoveImage(userID, recipeID, imageID string, expectedVersion *int) (dto.RecipeResponse, error) {
+	return s.editMedia(userID, recipeID, expectedVersion, func(tx *gorm.DB, r models.Recipe) ([]string, error) {
+		var image models.RecipeImage
+		if err := tx.First(&image, "id = ? AND recipe_id = ?", imageID, r.ID).Error; err != nil {
+			return nil, ErrImageNotFound
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return nil, err
+		}
+
+		ids, err := imageOrder(tx, r.ID, image.StepID)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeOrder(tx, &models.RecipeImage{}, "position", ids, nil); err != nil {
+			return nil, err
+		}
+
+		if image.IsCover {
+			next, err := firstGalleryImage(tx, r.ID)
+			if err != nil {
+				return nil, err
+			}
+			if err := setCover(tx, r.ID, next); err != nil {
+				return nil, err
+			}
+		}
+		return []string{image.Key}, nil
+	})
+}
+
+// ReorderImages puts the gallery images, or the images of one step, in the
+// given order. ids must list every image of that place exactly once.
+func (s *RecipeService) ReorderImages(userID, recipeID string, expectedVersion *int, ids []uuid.UUID) (dto.RecipeResponse, error) {
+	return s.editMedia(userID, recipeID, expectedVersion, func(tx *gorm.DB, r models.Recipe) ([]string, error) {
+		if len(ids) == 0 {
+			return nil, errors.New("ids must not be empty")
+		}
+		var first models.RecipeImage
+		if err := tx.First(&first, "id = ? AND recipe_id = ?", ids[0], r.ID).Error; err != nil {
+			return nil, ErrImageNotFound
+		}
+		current, err := imageOrder(tx, r.ID, first.StepID)
+		if err != nil {
+			return nil, err
+		}
+		if !sameIDs(ids, current) {
+			return nil, errors.New("ids must list every image of the gallery or of the step exactly once")
+		}
+		return nil, writeOrder(tx, &models.RecipeImage{}, "position", ids, nil)
+	})
+}
+
+// SetCoverImage makes an existing gallery image the recipe's cover.
+func (s *RecipeService) SetCoverImage(userID, recipeID, imageID string, expectedVersion *int) (dto.RecipeResponse, error) {
+	return s.editMedia(userID, recipeID, expectedVersion, func(tx *gorm.DB, r models.Recipe) ([]string, error) {
+		var image models.RecipeImage
+		if err := tx.First(&image, "id = ? AND recipe_id = ?", imageID, r.ID).Error; err != nil {
+			return nil, ErrImageNotFound
+		}
+		if image.StepID != nil {
+			return nil, ErrCoverInStep
+		}
+		return nil, setCover(tx, r.ID, &image)
+	})
+}
+
+// SetThumbnail replaces the cover image of one of the user's recipes.
+// Passing a nil file removes the cover image.
+func (s *RecipeService) SetThumbnail(userID, recipeID string, file *multipart.FileHeader) (dto.RecipeResponse, error) {
+	key := ""
+	var placeholder models.ImagePlaceholder
+	if file != nil {
+		var err error
+		if key, placeholder, err = s.SaveImage(file); err != nil {
+			return dto.RecipeResponse{}, err
+		}
+	}
+
+	res, err := s.editMedia(userID, recipeID, nil, func(tx *gorm.DB, r models.Recipe) ([]string, error) {
+		return replaceCover(tx, r.ID, key, placeholder)
+	})
+	if err != nil {
+		_ = s.DeleteImage(key)
+	}
+	return res, err
+}
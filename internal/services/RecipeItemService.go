@@ -0,0 +1,642 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIngredientNotFound = errors.New("ingredient not found")
+	ErrStepNotFound       = errors.New("step not found")
+)
+
+func orderedIngredients(db *gorm.DB) *gorm.DB {
+	return db.Order("ingredients.position ASC")
+}
+
+func orderedSteps(db *gorm.DB) *gorm.DB {
+	return db.Order("steps.number ASC")
+}
+
+// syncIngredients makes the recipe's ingredients match the given list while
+// keeping the IDs of rows that survive the edit. A row is matched by its ID
+// when one is given, otherwise by name. Positions follow the list order with
+// the ingredients of each group pulled together.
+func syncIngredients(tx *gorm.DB, recipeID uuid.UUID, items []models.Ingredient) error {
+	var existing []models.Ingredient
+	if err := tx.Where("recipe_id = ?", recipeID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]bool, len(existing))
+	for _, e := range existing {
+		byID[e.ID] = true
+	}
+
+	claimed := map[uuid.UUID]bool{}
+	for i := range items {
+		if items[i].ID == uuid.Nil {
+			continue
+		}
+		if !byID[items[i].ID] || claimed[items[i].ID] {
+			return ErrIngredientNotFound
+		}
+		claimed[items[i].ID] = true
+	}
+	key := func(name string) string { return strings.ToLower(strings.TrimSpace(name)) }
+	for i := range items {
+		if items[i].ID != uuid.Nil {
+			continue
+		}
+		for _, e := range existing {
+			if !claimed[e.ID] && key(e.Name) == key(items[i].Name) {
+				items[i].ID = e.ID
+				claimed[e.ID] = true
+				break
+			}
+		}
+	}
+
+	order := groupOrder(len(items), func(i int) string { return items[i].Group })
+	for pos, i := range order {
+		items[i].RecipeID = recipeID
+		items[i].Position = pos + 1
+		if byID[items[i].ID] {
+			if err := tx.Save(&items[i]).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if items[i].ID == uuid.Nil {
+			items[i].ID = uuid.New()
+		}
+		if err := tx.Create(&items[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, e := range existing {
+		if !claimed[e.ID] {
+			if err := tx.Delete(&models.Ingredient{}, "id = ?", e.ID).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncSteps makes the recipe's steps match the given list while keeping the
+// IDs of rows that survive the edit. A row is matched by its ID when one is
+// given, otherwise by step number. The steps are then numbered 1..n by their
+// given number with the steps of each section pulled together.
+func syncSteps(tx *gorm.DB, recipeID uuid.UUID, items []models.Step) error {
+	var existing []models.Step
+	if err := tx.Where("recipe_id = ?", recipeID).Find(&existing).Error; err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]bool, len(existing))
+	for _, e := range existing {
+		byID[e.ID] = true
+	}
+
+	claimed := map[uuid.UUID]bool{}
+	for i := range items {
+		if items[i].ID == uuid.Nil {
+			continue
+		}
+		if !byID[items[i].ID] || claimed[items[i].ID] {
+			return ErrStepNotFound
+		}
+		claimed[items[i].ID] = true
+	}
+	for i := range items {
+		if items[i].ID != uuid.Nil {
+			continue
+		}
+		for _, e := range existing {
+			if !claimed[e.ID] && e.Number == items[i].Number {
+				items[i].ID = e.ID
+				claimed[e.ID] = true
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(a, b int) bool { return items[a].Number < items[b].Number })
+	order := groupOrder(len(items), func(i int) string { return items[i].Section })
+	for number, i := range order {
+		items[i].RecipeID = recipeID
+		items[i].Number = number + 1
+		if byID[items[i].ID] {
+			if err := tx.Save(&items[i]).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if items[i].ID == uuid.Nil {
+			items[i].ID = uuid.New()
+		}
+		if err := tx.Create(&items[i]).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, e := range existing {
+		if !claimed[e.ID] {
+			if err := tx.Delete(&models.Step{}, "id = ?", e.ID).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stepTiming validates the duration and temperature an author set on a step.
+// Zero values mean "not set" so the step falls back to its text.
+func stepTiming(duration *int, temperature *float64, unit string) (*int, *float64, string, error) {
+	if duration != nil && *duration < 0 {
+		return nil, nil, "", errors.New("duration_seconds cannot be negative")
+	}
+	if duration != nil && *duration == 0 {
+		duration = nil
+	}
+	if temperature == nil || *temperature == 0 {
+		return duration, nil, "", nil
+	}
+
+	unit = strings.ToUpper(strings.TrimSpace(unit))
+	if unit == "" {
+		unit = "C"
+	}
+	if unit != "C" && unit != "F" {
+		return nil, nil, "", errors.New("temperature_unit must be C or F")
+	}
+	return duration, temperature, unit, nil
+}
+
+// stepDuration returns the duration of a step in seconds and whether it was
+// read from the step text.
+func stepDuration(st models.Step) (*int, bool) {
+	if st.DurationSeconds != nil {
+		return st.DurationSeconds, false
+	}
+	if seconds, ok := utils.ExtractDuration(st.Detail); ok {
+		return &seconds, true
+	}
+	return nil, false
+}
+
+// stepTemperature returns the temperature of a step and whether it was read
+// from the step text.
+func stepTemperature(st models.Step) (*float64, string, bool) {
+	if st.Temperature != nil {
+		return st.Temperature, st.TemperatureUnit, false
+	}
+	if value, unit, ok := utils.ExtractTemperature(st.Detail); ok {
+		return &value, unit, true
+	}
+	return nil, "", false
+}
+
+// moveTo returns ids with id moved to the 1-based position, clamped to the
+// list bounds. A position of 0 or less appends.
+func moveTo(ids []uuid.UUID, id uuid.UUID, position int) []uuid.UUID {
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, other := range ids {
+		if other != id {
+			out = append(out, other)
+		}
+	}
+	if position <= 0 || position > len(out) {
+		return append(out, id)
+	}
+	out = append(out, uuid.Nil)
+	copy(out[position:], out[position-1:])
+	out[position-1] = id
+	return out
+}
+
+// groupOrder returns the indexes 0..n-1 with items of the same group pulled
+// together. Groups keep the order in which they first appear.
+func groupOrder(n int, group func(i int) string) []int {
+	var names []string
+	byName := map[string][]int{}
+	for i := 0; i < n; i++ {
+		g := group(i)
+		if _, ok := byName[g]; !ok {
+			names = append(names, g)
+		}
+		byName[g] = append(byName[g], i)
+	}
+	out := make([]int, 0, n)
+	for _, g := range names {
+		out = append(out, byName[g]...)
+	}
+	return out
+}
+
+// groupNames lists the group names in order. It returns nil when no item is
+// in a named group.
+func groupNames(n int, group func(i int) string) []string {
+	var names []string
+	seen := map[string]bool{}
+	named := false
+	for i := 0; i < n; i++ {
+		g := group(i)
+		named = named || g != ""
+		if !seen[g] {
+			seen[g] = true
+			names = append(names, g)
+		}
+	}
+	if !named {
+		return nil
+	}
+	return names
+}
+
+// writeOrder numbers the rows 1..n in the given order, keeping the rows of
+// each group together.
+func writeOrder(tx *gorm.DB, model interface{}, column string, ids []uuid.UUID, groups map[uuid.UUID]string) error {
+	for i, idx := range groupOrder(len(ids), func(i int) string { return groups[ids[i]] }) {
+		if err := tx.Model(model).Where("id = ?", ids[idx]).UpdateColumn(column, i+1).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameIDs reports whether ids is a permutation of want.
+func sameIDs(ids, want []uuid.UUID) bool {
+	if len(ids) != len(want) {
+		return false
+	}
+	seen := make(map[uuid.UUID]bool, len(want))
+	for _, id := range want {
+		seen[id] = true
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
+
+// ingredientOrder returns the recipe's ingredient IDs in order along with
+// the group of each ingredient.
+func ingredientOrder(tx *gorm.DB, recipeID uuid.UUID) ([]uuid.UUID, map[uuid.UUID]string, error) {
+	var rows []models.Ingredient
+	if err := orderedIngredients(tx.Select("id, group_name")).Where("recipe_id = ?", recipeID).Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(rows))
+	groups := make(map[uuid.UUID]string, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+		groups[row.ID] = row.Group
+	}
+	return ids, groups, nil
+}
+
+// stepOrder returns the recipe's step IDs in order along with the section of
+// each step.
+func stepOrder(tx *gorm.DB, recipeID uuid.UUID) ([]uuid.UUID, map[uuid.UUID]string, error) {
+	var rows []models.Step
+	if err := orderedSteps(tx.Select("id, section")).Where("recipe_id = ?", recipeID).Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(rows))
+	sections := make(map[uuid.UUID]string, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+		sections[row.ID] = row.Section
+	}
+	return ids, sections, nil
+}
+
+// editRecipe runs a change to one of the user's recipes and records it like
+// a full update: a revision is stored, the version bumped and, when the
+// ingredients changed, nutrition and labels are recalculated.
+func (s *RecipeService) editRecipe(userID, recipeID string, expectedVersion *int, ingredientsChanged bool, fn func(tx *gorm.DB, r models.Recipe) error) (dto.RecipeResponse, error) {
+	var out dto.RecipeResponse
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		r, err := lockOwnedRecipe(tx, userID, recipeID, expectedVersion)
+		if err != nil {
+			return err
+		}
+		if err := ensureBaseRevision(tx, r); err != nil {
+			return err
+		}
+		if err := fn(tx, r); err != nil {
+			return err
+		}
+
+		if ingredientsChanged {
+			if err := s.Nutrition.RecalculateRecipe(tx, r.ID); err != nil {
+				return err
+			}
+			if err := RecalculateLabels(tx, r.ID); err != nil {
+				return err
+			}
+		}
+		if err := recordRevision(tx, r.ID, r.UserID); err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, r.ID); err != nil {
+			return err
+		}
+
+		if err := preloadRecipe(tx).First(&r, "id = ?", r.ID).Error; err != nil {
+			return err
+		}
+		out = toRecipeResponse(r, userID)
+		return nil
+	})
+	return out, err
+}
+
+// viewableRecipe loads a recipe the viewer may see.
+func (s *RecipeService) viewableRecipe(recipeID, viewerID string) (models.Recipe, error) {
+	var r models.Recipe
+	if err := s.DB.First(&r, "id = ?", recipeID).Error; err != nil || !canView(r, viewerID) {
+		return r, ErrRecipeNotFound
+	}
+	return r, nil
+}
+
+// GetIngredient returns one ingredient of a recipe the viewer may see.
+func (s *RecipeService) GetIngredient(recipeID, ingredientID, viewerID string) (dto.IngredientResponse, error) {
+	r, err := s.viewableRecipe(recipeID, viewerID)
+	if err != nil {
+		return dto.IngredientResponse{}, err
+	}
+	var ing models.Ingredient
+	if err := s.DB.First(&ing, "id = ? AND recipe_id = ?", ingredientID, r.ID).Error; err != nil {
+		return dto.IngredientResponse{}, ErrIngredientNotFound
+	}
+	return toIngredientResponses([]models.Ingredient{ing})[0], nil
+}
+
+// AddIngredient adds an ingredient at the requested position, or at the end.
+func (s *RecipeService) AddIngredient(userID, recipeID string, expectedVersion *int, req dto.IngredientRequest) (dto.RecipeResponse, error) {
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Amount) == "" {
+		return dto.RecipeResponse{}, errors.New("ingredient name and amount are required")
+	}
+	return s.editRecipe(userID, recipeID, expectedVersion, true, func(tx *gorm.DB, r models.Recipe) error {
+		ids, groups, err := ingredientOrder(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		ing := newIngredient(r.ID, dto.IngredientInput{
+			Name:     req.Name,
+			Amount:   req.Amount,
+			Quantity: req.Quantity,
+			Unit:     req.Unit,
+			FoodID:   req.FoodID,
+			Group:    req.Group,
+		})
+		if ing.FoodID != nil {
+			if err := tx.First(&models.Food{}, "id = ?", *ing.FoodID).Error; err != nil {
+				return errors.New("food not found")
+			}
+		}
+		if err := tx.Create(&ing).Error; err != nil {
+			return err
+		}
+		groups[ing.ID] = ing.Group
+
+		position := 0
+		if req.Position != nil {
+			position = *req.Position
+		}
+		return writeOrder(tx, &models.Ingredient{}, "position", moveTo(ids, ing.ID, position), groups)
+	})
+}
+
+// UpdateIngredient changes the fields of one ingredient that are set in the
+// request. A new amount without a quantity is parsed again.
+func (s *RecipeService) UpdateIngredient(userID, recipeID, ingredientID string, expectedVersion *int, req dto.UpdateIngredientRequest) (dto.RecipeResponse, error) {
+	return s.editRecipe(userID, recipeID, expectedVersion, true, func(tx *gorm.DB, r models.Recipe) error {
+		var ing models.Ingredient
+		if err := tx.First(&ing, "id = ? AND recipe_id = ?", ingredientID, r.ID).Error; err != nil {
+			return ErrIngredientNotFound
+		}
+
+		if req.Name != nil {
+			if strings.TrimSpace(*req.Name) == "" {
+				return errors.New("ingredient name cannot be empty")
+			}
+			ing.Name = *req.Name
+		}
+		if req.Amount != nil {
+			if strings.TrimSpace(*req.Amount) == "" {
+				return errors.New("ingredient amount cannot be empty")
+			}
+			ing.Amount = *req.Amount
+			if req.Quantity == nil {
+				ing.Quantity = nil
+				if qty, unit, _, ok := utils.ParseAmount(ing.Amount); ok {
+					ing.Quantity = &qty
+					ing.Unit = unit
+				}
+			}
+		}
+		if req.Quantity != nil {
+			ing.Quantity = req.Quantity
+		}
+		if req.Unit != nil {
+			ing.Unit = utils.NormalizeUnit(*req.Unit)
+		}
+		if req.Group != nil {
+			ing.Group = strings.TrimSpace(*req.Group)
+		}
+		if err := tx.Save(&ing).Error; err != nil {
+			return err
+		}
+
+		if req.Position == nil && req.Group == nil {
+			return nil
+		}
+		ids, groups, err := ingredientOrder(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		if req.Position != nil {
+			ids = moveTo(ids, ing.ID, *req.Position)
+		}
+		return writeOrder(tx, &models.Ingredient{}, "position", ids, groups)
+	})
+}
+
+// DeleteIngredient removes one ingredient and closes the gap it leaves.
+func (s *RecipeService) DeleteIngredient(userID, recipeID, ingredientID string, expectedVersion *int) (dto.RecipeResponse, error) {
+	return s.editRecipe(userID, recipeID, expectedVersion, true, func(tx *gorm.DB, r models.Recipe) error {
+		res := tx.Where("recipe_id = ?", r.ID).Delete(&models.Ingredient{}, "id = ?", ingredientID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrIngredientNotFound
+		}
+		ids, groups, err := ingredientOrder(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		return writeOrder(tx, &models.Ingredient{}, "position", ids, groups)
+	})
+}
+
+// ReorderIngredients puts the ingredients in the given order. ids must list
+// every ingredient of the recipe exactly once.
+func (s *RecipeService) ReorderIngredients(userID, recipeID string, expectedVersion *int, ids []uuid.UUID) (dto.RecipeResponse, error) {
+	return s.editRecipe(userID, recipeID, expectedVersion, false, func(tx *gorm.DB, r models.Recipe) error {
+		current, groups, err := ingredientOrder(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		if !sameIDs(ids, current) {
+			return errors.New("ids must list every ingredient of the recipe exactly once")
+		}
+		return writeOrder(tx, &models.Ingredient{}, "position", ids, groups)
+	})
+}
+
+// GetStep returns one step of a recipe the viewer may see.
+func (s *RecipeService) GetStep(recipeID, stepID, viewerID string) (dto.StepResponse, error) {
+	r, err := s.viewableRecipe(recipeID, viewerID)
+	if err != nil {
+		return dto.StepResponse{}, err
+	}
+	var st models.Step
+	if err := s.DB.Preload("Images", orderedImages).First(&st, "id = ? AND recipe_id = ?", stepID, r.ID).Error; err != nil {
+		return dto.StepResponse{}, ErrStepNotFound
+	}
+	return toStepResponses([]models.Step{st}, privateMedia(r))[0], nil
+}
+
+// AddStep inserts a step at the requested number, or appends it. The steps
+// are renumbered 1..n afterwards.
+func (s *RecipeService) AddStep(userID, recipeID string, expectedVersion *int, req dto.StepRequest) (dto.RecipeResponse, error) {
+	if strings.TrimSpace(req.Detail) == "" {
+		return dto.RecipeResponse{}, errors.New("step detail is required")
+	}
+	return s.editRecipe(userID, recipeID, expectedVersion, false, func(tx *gorm.DB, r models.Recipe) error {
+		ids, groups, err := stepOrder(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		st, err := newStep(r.ID, dto.StepInput{
+			Number:          len(ids) + 1,
+			Detail:          req.Detail,
+			Section:         req.Section,
+			DurationSeconds: req.DurationSeconds,
+			Temperature:     req.Temperature,
+			TemperatureUnit: req.TemperatureUnit,
+		})
+		if err != nil {
+			return err
+		}
+		st.ID = uuid.New()
+		if err := tx.Create(&st).Error; err != nil {
+			return err
+		}
+		groups[st.ID] = st.Section
+
+		number := 0
+		if req.Number != nil {
+			number = *req.Number
+		}
+		return writeOrder(tx, &models.Step{}, "number", moveTo(ids, st.ID, number), groups)
+	})
+}
+
+// UpdateStep edits the text or section of a step and moves it when a new
+// number is given, renumbering the other steps.
+func (s *RecipeService) UpdateStep(userID, recipeID, stepID string, expectedVersion *int, req dto.UpdateStepRequest) (dto.RecipeResponse, error) {
+	return s.editRecipe(userID, recipeID, expectedVersion, false, func(tx *gorm.DB, r models.Recipe) error {
+		var st models.Step
+		if err := tx.First(&st, "id = ? AND recipe_id = ?", stepID, r.ID).Error; err != nil {
+			return ErrStepNotFound
+		}
+
+		if req.Detail != nil {
+			if strings.TrimSpace(*req.Detail) == "" {
+				return errors.New("step detail cannot be empty")
+			}
+			st.Detail = *req.Detail
+		}
+		if req.Section != nil {
+			st.Section = strings.TrimSpace(*req.Section)
+		}
+		if req.DurationSeconds != nil {
+			st.DurationSeconds = req.DurationSeconds
+		}
+		if req.Temperature != nil {
+			st.Temperature = req.Temperature
+		}
+		if req.TemperatureUnit != nil {
+			st.TemperatureUnit = *req.TemperatureUnit
+		}
+		var err error
+		st.DurationSeconds, st.Temperature, st.TemperatureUnit, err = stepTiming(st.DurationSeconds, st.Temperature, st.TemperatureUnit)
+		if err != nil {
+			return err
+		}
+		if err := tx.Save(&st).Error; err != nil {
+			return err
+		}
+
+		if req.Number == nil && req.Section == nil {
+			return nil
+		}
+		ids, groups, err := stepOrder(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		if req.Number != nil {
+			ids = moveTo(ids, st.ID, *req.Number)
+		}
+		return writeOrder(tx, &models.Step{}, "number", ids, groups)
+	})
+}
+
+// DeleteStep removes a step and renumbers the ones after it.
+func (s *RecipeService) DeleteStep(userID, recipeID, stepID string, expectedVersion *int) (dto.RecipeResponse, error) {
+	return s.editRecipe(userID, recipeID, expectedVersion, false, func(tx *gorm.DB, r models.Recipe) error {
+		res := tx.Where("recipe_id = ?", r.ID).Delete(&models.Step{}, "id = ?", stepID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrStepNotFound
+		}
+		ids, groups, err := stepOrder(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		return writeOrder(tx, &models.Step{}, "number", ids, groups)
+	})
+}
+
+// ReorderSteps renumbers the steps in the given order in one transaction.
+// ids must list every step of the recipe exactly once.
+func (s *RecipeService) ReorderSteps(userID, recipeID string, expectedVersion *int, ids []uuid.UUID) (dto.RecipeResponse, error) {
+	return s.editRecipe(userID, recipeID, expectedVersion, false, func(tx *gorm.DB, r models.Recipe) error {
+		current, groups, err := stepOrder(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		if !sameIDs(ids, current) {
+			return errors.New("ids must list every step of the recipe exactly once")
+		}
+		return writeOrder(tx, &models.Step{}, "number", ids, groups)
+	})
+}
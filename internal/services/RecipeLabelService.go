@@ -0,0 +1,278 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	AllergenLabels = []string{"gluten", "dairy", "nuts", "shellfish", "egg", "soy"}
+	DietLabels     = []string{"vegan", "vegetarian", "halal-friendly", "gluten-free"}
+)
+
+// dietExclusions lists the food flags that rule out each diet tag.
+var dietExclusions = map[string][]string{
+	"vegan":          {"meat", "pork", "fish", "shellfish", "dairy", "egg", "honey"},
+	"vegetarian":     {"meat", "pork", "fish", "shellfish"},
+	"halal-friendly": {"pork", "alcohol"},
+}
+
+// nameFlags catches ingredients the nutrient dataset does not cover but
+// that matter for diet tags, matched as whole words in the ingredient name.
+var nameFlags = map[string][]string{
+	"babi":    {"pork", "meat"},
+	"pork":    {"pork", "meat"},
+	"bacon":   {"pork", "meat"},
+	"ham":     {"pork", "meat"},
+	"lard":    {"pork", "meat"},
+	"angciu":  {"alcohol"},
+	"arak":    {"alcohol"},
+	"mirin":   {"alcohol"},
+	"rum":     {"alcohol"},
+	"wine":    {"alcohol"},
+	"beer":    {"alcohol"},
+	"bir":     {"alcohol"},
+	"sake":    {"alcohol"},
+	"brandy":  {"alcohol"},
+	"ang ciu": {"alcohol"},
+}
+
+func isKnownLabel(label string) bool {
+	for _, l := range AllergenLabels {
+		if l == label {
+			return true
+		}
+	}
+	for _, l := range DietLabels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllergen(label string) bool {
+	for _, l := range AllergenLabels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateLabels checks that every label is a known allergen or diet tag.
+func ValidateLabels(labels []string) error {
+	for _, l := range labels {
+		if !isKnownLabel(l) {
+			return fmt.Errorf("unknown label %q", l)
+		}
+	}
+	return nil
+}
+
+func joinLabels(set map[string]bool) string {
+	if len(set) == 0 {
+		return ""
+	}
+	labels := make([]string, 0, len(set))
+	for l := range set {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	return "|" + strings.Join(labels, "|") + "|"
+}
+
+func splitLabels(s string) []string {
+	out := []string{}
+	for _, l := range strings.Split(strings.Trim(s, "|"), "|") {
+		if l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func parseOverrides(s string) map[string]bool {
+	if s == "" {
+		return nil
+	}
+	var overrides map[string]bool
+	if err := json.Unmarshal([]byte(s), &overrides); err != nil {
+		return nil
+	}
+	return overrides
+}
+
+// deriveLabels works out allergens and diet tags from the foods the
+// ingredients are mapped to. Diet tags are only claimed when every
+// ingredient could be identified.
+func deriveLabels(ingredients []models.Ingredient, foods map[uuid.UUID]models.Food) (allergens, diets map[string]bool) {
+	allergens = map[string]bool{}
+	flags := map[string]bool{}
+	identified := true
+
+	for _, ing := range ingredients {
+		words := " " + strings.Join(tokenize(ing.Name), " ") + " "
+		flagged := false
+		for keyword, fs := range nameFlags {
+			if strings.Contains(words, " "+keyword+" ") {
+				flagged = true
+				for _, f := range fs {
+					flags[f] = true
+				}
+			}
+		}
+
+		if ing.FoodID == nil {
+			if !flagged {
+				identified = false
+			}
+			continue
+		}
+		food, ok := foods[*ing.FoodID]
+		if !ok {
+			identified = false
+			continue
+		}
+		for _, a := range splitLabels(food.Allergens) {
+			allergens[a] = true
+		}
+		for _, f := range splitLabels(food.DietFlags) {
+			flags[f] = true
+		}
+	}
+
+	diets = map[string]bool{}
+	if !identified || len(ingredients) == 0 {
+		return allergens, diets
+	}
+	for diet, excluded := range dietExclusions {
+		ok := true
+		for _, f := range excluded {
+			if flags[f] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			diets[diet] = true
+		}
+	}
+	if !allergens["gluten"] {
+		diets["gluten-free"] = true
+	}
+	return allergens, diets
+}
+
+// RecalculateLabels derives the recipe's labels from its ingredients,
+// applies the author's overrides and stores the result.
+func RecalculateLabels(tx *gorm.DB, recipeID uuid.UUID) error {
+	var recipe models.Recipe
+	if err := tx.Preload("Ingredients").First(&recipe, "id = ?", recipeID).Error; err != nil {
+		return ErrRecipeNotFound
+	}
+
+	var foodIDs []uuid.UUID
+	for _, ing := range recipe.Ingredients {
+		if ing.FoodID != nil {
+			foodIDs = append(foodIDs, *ing.FoodID)
+		}
+	}
+	foods := map[uuid.UUID]models.Food{}
+	if len(foodIDs) > 0 {
+		var list []models.Food
+		if err := tx.Where("id IN ?", foodIDs).Find(&list).Error; err != nil {
+			return err
+		}
+		for _, f := range list {
+			foods[f.ID] = f
+		}
+	}
+
+	allergens, diets := deriveLabels(recipe.Ingredients, foods)
+	for label, present := range parseOverrides(recipe.LabelOverrides) {
+		target := diets
+		if isAllergen(label) {
+			target = allergens
+		}
+		if present {
+			target[label] = true
+		} else {
+			delete(target, label)
+		}
+	}
+
+	return tx.Model(&models.Recipe{}).Where("id = ?", recipe.ID).UpdateColumns(map[string]interface{}{
+		"allergens": joinLabels(allergens),
+		"diet_tags": joinLabels(diets),
+	}).Error
+}
+
+// SetLabelOverrides replaces the author's allergen and diet overrides. An
+// empty map clears them so the derived labels apply again.
+func (s *RecipeService) SetLabelOverrides(userID, recipeID string, overrides map[string]bool) (dto.RecipeResponse, error) {
+	for label := range overrides {
+		if !isKnownLabel(label) {
+			return dto.RecipeResponse{}, fmt.Errorf("unknown label %q", label)
+		}
+	}
+
+	var out dto.RecipeResponse
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var r models.Recipe
+		if err := tx.First(&r, "id = ?", recipeID).Error; err != nil {
+			return ErrRecipeNotFound
+		}
+		if r.UserID.String() != userID {
+			return ErrForbidden
+		}
+
+		encoded := ""
+		if len(overrides) > 0 {
+			b, err := json.Marshal(overrides)
+			if err != nil {
+				return err
+			}
+			encoded = string(b)
+		}
+		if err := tx.Model(&r).UpdateColumn("label_overrides", encoded).Error; err != nil {
+			return err
+		}
+		if err := RecalculateLabels(tx, r.ID); err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, r.ID); err != nil {
+			return err
+		}
+
+		if err := preloadRecipe(tx).First(&r, "id = ?", r.ID).Error; err != nil {
+			return err
+		}
+
+		out = toRecipeResponse(r, userID)
+		return nil
+	})
+	return out, err
+}
+
+// applyLabelFilter restricts a recipe query to the requested diet tags and
+// allergens.
+func applyLabelFilter(db *gorm.DB, filter dto.RecipeFilter) *gorm.DB {
+	for _, d := range filter.Diet {
+		db = db.Where("recipes.diet_tags LIKE ?", "%|"+d+"|%")
+	}
+	for _, a := range filter.Allergens {
+		db = db.Where("recipes.allergens LIKE ?", "%|"+a+"|%")
+	}
+	for _, a := range filter.ExcludeAllergens {
+		db = db.Where("COALESCE(recipes.allergens, '') NOT LIKE ?", "%|"+a+"|%")
+	}
+	return db
+}
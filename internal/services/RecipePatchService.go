@@ -0,0 +1,133 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+)
+
+// RecipeDocumentFrom extracts the writable fields of a recipe response.
+func RecipeDocumentFrom(res dto.RecipeResponse) dto.RecipeDocument {
+	doc := dto.RecipeDocument{
+		Title:       res.Title,
+		Description: res.Description,
+		Category:    res.CategorySlug,
+		PrepTime:    res.PrepTime,
+		CookTime:    res.CookTime,
+		Servings:    res.Servings,
+		Status:      res.Status,
+		PublishAt:   res.PublishAt,
+		Visibility:  res.Visibility,
+		Tags:        []string{},
+		Ingredients: []dto.IngredientInput{},
+		Steps:       []dto.StepInput{},
+	}
+	for _, t := range res.Tags {
+		doc.Tags = append(doc.Tags, t.Name)
+	}
+	for _, in := range res.Ingredients {
+		id := in.ID
+		input := dto.IngredientInput{ID: &id, Name: in.Name, Amount: in.Amount, Quantity: in.Quantity, Unit: in.Unit, Group: in.Group}
+		if in.FoodManual {
+			input.FoodID = in.FoodID
+		}
+		doc.Ingredients = append(doc.Ingredients, input)
+	}
+	for _, st := range res.Steps {
+		id := st.ID
+		step := dto.StepInput{ID: &id, Number: st.Number, Detail: st.Detail, Section: st.Section}
+		if !st.DurationDetected {
+			step.DurationSeconds = st.DurationSeconds
+		}
+		if !st.TemperatureDetected {
+			step.Temperature = st.Temperature
+			step.TemperatureUnit = st.TemperatureUnit
+		}
+		doc.Steps = append(doc.Steps, step)
+	}
+	return doc
+}
+
+// ApplyMergePatch applies an RFC 7396 merge patch to a recipe document and
+// returns the update for the fields the patch touches. A null removes the
+// value: text fields and lists become empty, numbers zero and publish_at
+// unset; title, status and visibility cannot be removed.
+func ApplyMergePatch(doc dto.RecipeDocument, patch []byte) (dto.UpdateRecipeRequest, error) {
+	var req dto.UpdateRecipeRequest
+
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return req, errors.New("invalid merge patch: " + err.Error())
+	}
+	patchObj, ok := patchValue.(map[string]interface{})
+	if !ok {
+		return req, errors.New("merge patch must be a JSON object")
+	}
+
+	current, err := json.Marshal(doc)
+	if err != nil {
+		return req, err
+	}
+	var target interface{}
+	if err := json.Unmarshal(current, &target); err != nil {
+		return req, err
+	}
+
+	merged, err := json.Marshal(utils.MergePatch(target, patchValue))
+	if err != nil {
+		return req, err
+	}
+	var result dto.RecipeDocument
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return req, errors.New("invalid merge patch: " + err.Error())
+	}
+
+	for key := range patchObj {
+		switch key {
+		case "title":
+			req.Title = &result.Title
+		case "description":
+			req.Description = &result.Description
+		case "category":
+			req.Category = &result.Category
+		case "prep_time":
+			req.PrepTime = &result.PrepTime
+		case "cook_time":
+			req.CookTime = &result.CookTime
+		case "servings":
+			req.Servings = &result.Servings
+		case "status":
+			req.Status = &result.Status
+		case "publish_at":
+			// Removing publish_at is only meaningful together with a status
+			// change, which clears it anyway.
+			req.PublishAt = result.PublishAt
+		case "visibility":
+			req.Visibility = &result.Visibility
+		case "tags":
+			req.Tags = nonNil(result.Tags)
+		case "ingredients":
+			req.Ingredients = result.Ingredients
+			if req.Ingredients == nil {
+				req.Ingredients = []dto.IngredientInput{}
+			}
+		case "steps":
+			req.Steps = result.Steps
+			if req.Steps == nil {
+				req.Steps = []dto.StepInput{}
+			}
+		default:
+			return req, errors.New("field " + key + " cannot be patched")
+		}
+	}
+	return req, nil
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
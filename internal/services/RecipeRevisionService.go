@@ -0,0 +1,348 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// snapshotRecipe captures the current content of a recipe.
+func snapshotRecipe(tx *gorm.DB, recipeID uuid.UUID) (dto.RecipeSnapshot, error) {
+	var r models.Recipe
+	err := tx.
+		Preload("Ingredients", orderedIngredients).
+		Preload("Steps", orderedSteps).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") }).
+		First(&r, "id = ?", recipeID).Error
+	if err != nil {
+		return dto.RecipeSnapshot{}, ErrRecipeNotFound
+	}
+
+	snap := dto.RecipeSnapshot{
+		Title:       r.Title,
+		Description: r.Description,
+		CategoryID:  r.CategoryID,
+		PrepTime:    r.PrepTime,
+		CookTime:    r.CookTime,
+		Servings:    r.Servings,
+		Tags:        []string{},
+		Ingredients: []dto.SnapshotIngredient{},
+		Steps:       []dto.StepInput{},
+	}
+	for _, t := range r.Tags {
+		snap.Tags = append(snap.Tags, t.Name)
+	}
+	for _, in := range r.Ingredients {
+		snap.Ingredients = append(snap.Ingredients, dto.SnapshotIngredient{
+			Name:       in.Name,
+			Amount:     in.Amount,
+			Quantity:   in.Quantity,
+			Unit:       in.Unit,
+			FoodID:     in.FoodID,
+			FoodManual: in.FoodManual,
+			Group:      in.Group,
+		})
+	}
+	for _, st := range r.Steps {
+		snap.Steps = append(snap.Steps, dto.StepInput{
+			Number:          st.Number,
+			Detail:          st.Detail,
+			Section:         st.Section,
+			DurationSeconds: st.DurationSeconds,
+			Temperature:     st.Temperature,
+			TemperatureUnit: st.TemperatureUnit,
+		})
+	}
+	return snap, nil
+}
+
+// recordRevision stores the current content of the recipe as its next
+// revision.
+func recordRevision(tx *gorm.DB, recipeID, userID uuid.UUID) error {
+	snap, err := snapshotRecipe(tx, recipeID)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	var last int
+	if err := tx.Model(&models.RecipeRevision{}).
+		Where("recipe_id = ?", recipeID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&last).Error; err != nil {
+		return err
+	}
+
+	rev := models.RecipeRevision{RecipeID: recipeID, Number: last + 1, UserID: userID, Snapshot: string(b)}
+	return tx.Create(&rev).Error
+}
+
+// ensureBaseRevision records the state of a recipe created before revisions
+// existed, so its first edit can still be diffed and undone.
+func ensureBaseRevision(tx *gorm.DB, r models.Recipe) error {
+	var count int64
+	if err := tx.Model(&models.RecipeRevision{}).Where("recipe_id = ?", r.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordRevision(tx, r.ID, r.UserID)
+}
+
+func loadRevision(db *gorm.DB, recipeID uuid.UUID, number int) (models.RecipeRevision, dto.RecipeSnapshot, error) {
+	var rev models.RecipeRevision
+	var snap dto.RecipeSnapshot
+	if err := db.First(&rev, "recipe_id = ? AND number = ?", recipeID, number).Error; err != nil {
+		return rev, snap, ErrRevisionNotFound
+	}
+	if err := json.Unmarshal([]byte(rev.Snapshot), &snap); err != nil {
+		return rev, snap, err
+	}
+	return rev, snap, nil
+}
+
+// ListRevisions returns the revisions of one of the user's recipes, newest
+// first, without their snapshots.
+func (s *RecipeService) ListRevisions(userID, recipeID string) ([]dto.RevisionResponse, error) {
+	r, err := ownedRecipe(s.DB, userID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var revs []models.RecipeRevision
+	if err := s.DB.Select("id, recipe_id, number, user_id, created_at").
+		Where("recipe_id = ?", r.ID).
+		Order("number DESC").
+		Find(&revs).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]dto.RevisionResponse, 0, len(revs))
+	for _, rev := range revs {
+		out = append(out, dto.RevisionResponse{ID: rev.ID, Number: rev.Number, UserID: rev.UserID, CreatedAt: rev.CreatedAt})
+	}
+	return out, nil
+}
+
+// GetRevision returns a single revision including its snapshot.
+func (s *RecipeService) GetRevision(userID, recipeID string, number int) (dto.RevisionResponse, error) {
+	r, err := ownedRecipe(s.DB, userID, recipeID)
+	if err != nil {
+		return dto.RevisionResponse{}, err
+	}
+	rev, snap, err := loadRevision(s.DB, r.ID, number)
+	if err != nil {
+		return dto.RevisionResponse{}, err
+	}
+	return dto.RevisionResponse{ID: rev.ID, Number: rev.Number, UserID: rev.UserID, CreatedAt: rev.CreatedAt, Snapshot: &snap}, nil
+}
+
+// DiffRevisions compares two revisions of a recipe.
+func (s *RecipeService) DiffRevisions(userID, recipeID string, from, to int) (dto.RevisionDiff, error) {
+	r, err := ownedRecipe(s.DB, userID, recipeID)
+	if err != nil {
+		return dto.RevisionDiff{}, err
+	}
+	_, a, err := loadRevision(s.DB, r.ID, from)
+	if err != nil {
+		return dto.RevisionDiff{}, err
+	}
+	_, b, err := loadRevision(s.DB, r.ID, to)
+	if err != nil {
+		return dto.RevisionDiff{}, err
+	}
+
+	diff := diffSnapshots(a, b)
+	diff.From, diff.To = from, to
+	return diff, nil
+}
+
+func categoryRef(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.String()
+}
+
+func diffSnapshots(a, b dto.RecipeSnapshot) dto.RevisionDiff {
+	diff := dto.RevisionDiff{
+		Fields:             []dto.FieldChange{},
+		IngredientsAdded:   []dto.SnapshotIngredient{},
+		IngredientsRemoved: []dto.SnapshotIngredient{},
+		IngredientsChanged: []dto.IngredientChange{},
+		StepsAdded:         []dto.StepInput{},
+		StepsRemoved:       []dto.StepInput{},
+		StepsChanged:       []dto.StepChange{},
+	}
+
+	field := func(name string, from, to interface{}) {
+		if from != to {
+			diff.Fields = append(diff.Fields, dto.FieldChange{Field: name, From: from, To: to})
+		}
+	}
+	field("title", a.Title, b.Title)
+	field("description", a.Description, b.Description)
+	field("category_id", categoryRef(a.CategoryID), categoryRef(b.CategoryID))
+	field("prep_time", a.PrepTime, b.PrepTime)
+	field("cook_time", a.CookTime, b.CookTime)
+	field("servings", a.Servings, b.Servings)
+	if strings.Join(a.Tags, "|") != strings.Join(b.Tags, "|") {
+		diff.Fields = append(diff.Fields, dto.FieldChange{Field: "tags", From: a.Tags, To: b.Tags})
+	}
+	list := func(name string, from, to []string) {
+		if strings.Join(from, "|") != strings.Join(to, "|") {
+			diff.Fields = append(diff.Fields, dto.FieldChange{Field: name, From: from, To: to})
+		}
+	}
+	list("ingredient_groups",
+		groupNames(len(a.Ingredients), func(i int) string { return a.Ingredients[i].Group }),
+		groupNames(len(b.Ingredients), func(i int) string { return b.Ingredients[i].Group }))
+	list("step_sections",
+		groupNames(len(a.Steps), func(i int) string { return a.Steps[i].Section }),
+		groupNames(len(b.Steps), func(i int) string { return b.Steps[i].Section }))
+
+	key := func(name string) string { return strings.ToLower(strings.TrimSpace(name)) }
+	before := map[string]dto.SnapshotIngredient{}
+	for _, in := range a.Ingredients {
+		before[key(in.Name)] = in
+	}
+	after := map[string]bool{}
+	for _, in := range b.Ingredients {
+		after[key(in.Name)] = true
+		old, ok := before[key(in.Name)]
+		switch {
+		case !ok:
+			diff.IngredientsAdded = append(diff.IngredientsAdded, in)
+		case old.Amount != in.Amount:
+			diff.IngredientsChanged = append(diff.IngredientsChanged, dto.IngredientChange{Name: in.Name, From: old.Amount, To: in.Amount})
+		}
+	}
+	for _, in := range a.Ingredients {
+		if !after[key(in.Name)] {
+			diff.IngredientsRemoved = append(diff.IngredientsRemoved, in)
+		}
+	}
+
+	steps := map[int]string{}
+	for _, st := range a.Steps {
+		steps[st.Number] = st.Detail
+	}
+	seen := map[int]bool{}
+	for _, st := range b.Steps {
+		seen[st.Number] = true
+		old, ok := steps[st.Number]
+		switch {
+		case !ok:
+			diff.StepsAdded = append(diff.StepsAdded, st)
+		case old != st.Detail:
+			diff.StepsChanged = append(diff.StepsChanged, dto.StepChange{Number: st.Number, From: old, To: st.Detail})
+		}
+	}
+	for _, st := range a.Steps {
+		if !seen[st.Number] {
+			diff.StepsRemoved = append(diff.StepsRemoved, st)
+		}
+	}
+	return diff
+}
+
+// RestoreRevision puts the content of an earlier revision back in place. The
+// restore itself is recorded as a new revision, so it can be undone too.
+func (s *RecipeService) RestoreRevision(userID, recipeID string, number int) (dto.RecipeResponse, error) {
+	var out dto.RecipeResponse
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		r, err := ownedRecipe(tx, userID, recipeID)
+		if err != nil {
+			return err
+		}
+		_, snap, err := loadRevision(tx, r.ID, number)
+		if err != nil {
+			return err
+		}
+
+		// The category may have been deleted since the revision was taken.
+		if snap.CategoryID != nil {
+			var count int64
+			if err := tx.Model(&models.Category{}).Where("id = ?", *snap.CategoryID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				snap.CategoryID = nil
+			}
+		}
+
+		if err := tx.Model(&r).Updates(map[string]interface{}{
+			"title":       snap.Title,
+			"description": snap.Description,
+			"category_id": snap.CategoryID,
+			"prep_time":   snap.PrepTime,
+			"cook_time":   snap.CookTime,
+			"servings":    snap.Servings,
+		}).Error; err != nil {
+			return err
+		}
+
+		ings := make([]models.Ingredient, 0, len(snap.Ingredients))
+		for _, in := range snap.Ingredients {
+			ing := newIngredient(r.ID, dto.IngredientInput{
+				Name:     in.Name,
+				Amount:   in.Amount,
+				Quantity: in.Quantity,
+				Unit:     in.Unit,
+				Group:    in.Group,
+			})
+			ing.FoodID = in.FoodID
+			ing.FoodManual = in.FoodManual
+			ings = append(ings, ing)
+		}
+		if err := syncIngredients(tx, r.ID, ings); err != nil {
+			return err
+		}
+
+		steps := make([]models.Step, 0, len(snap.Steps))
+		for _, st := range snap.Steps {
+			step, err := newStep(r.ID, st)
+			if err != nil {
+				return err
+			}
+			steps = append(steps, step)
+		}
+		if err := syncSteps(tx, r.ID, steps); err != nil {
+			return err
+		}
+
+		if err := setRecipeTags(tx, &r, snap.Tags); err != nil {
+			return err
+		}
+		if err := s.Nutrition.RecalculateRecipe(tx, r.ID); err != nil {
+			return err
+		}
+		if err := RecalculateLabels(tx, r.ID); err != nil {
+			return err
+		}
+		if err := recordRevision(tx, r.ID, r.UserID); err != nil {
+			return err
+		}
+		if err := bumpVersion(tx, r.ID); err != nil {
+			return err
+		}
+
+		if err := preloadRecipe(tx).First(&r, "id = ?", r.ID).Error; err != nil {
+			return err
+		}
+		out = toRecipeResponse(r, userID)
+		return nil
+	})
+	return out, err
+}
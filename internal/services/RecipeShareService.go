@@ -0,0 +1,135 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrShareNotFound = errors.New("share link not found or no longer valid")
+
+func IsValidVisibility(visibility string) bool {
+	switch visibility {
+	case models.VisibilityPrivate, models.VisibilityUnlisted, models.VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+// newShareToken returns 32 random bytes encoded for use in a URL.
+func newShareToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func toShareResponse(s models.RecipeShare) dto.ShareResponse {
+	return dto.ShareResponse{
+		ID:        s.ID,
+		RecipeID:  s.RecipeID,
+		Token:     s.Token,
+		ExpiresAt: s.ExpiresAt,
+		RevokedAt: s.RevokedAt,
+		Active:    s.Active(time.Now()),
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// ownedRecipe loads a recipe and checks that userID is its author.
+func ownedRecipe(db *gorm.DB, userID, recipeID string) (models.Recipe, error) {
+	var r models.Recipe
+	if err := db.First(&r, "id = ?", recipeID).Error; err != nil {
+		return r, ErrRecipeNotFound
+	}
+	if r.UserID.String() != userID {
+		return r, ErrForbidden
+	}
+	return r, nil
+}
+
+// CreateShare creates a share link for one of the user's recipes. Private
+// recipes cannot be shared; make them unlisted first.
+func (s *RecipeService) CreateShare(userID, recipeID string, req dto.CreateShareRequest) (dto.ShareResponse, error) {
+	r, err := ownedRecipe(s.DB, userID, recipeID)
+	if err != nil {
+		return dto.ShareResponse{}, err
+	}
+	if r.Visibility == models.VisibilityPrivate {
+		return dto.ShareResponse{}, errors.New("private recipes cannot be shared, change the visibility to unlisted first")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return dto.ShareResponse{}, errors.New("expires_at must be in the future")
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return dto.ShareResponse{}, err
+	}
+	share := models.RecipeShare{RecipeID: r.ID, Token: token, ExpiresAt: req.ExpiresAt}
+	if err := s.DB.Create(&share).Error; err != nil {
+		return dto.ShareResponse{}, err
+	}
+	return toShareResponse(share), nil
+}
+
+// ListShares returns every share link of a recipe, newest first.
+func (s *RecipeService) ListShares(userID, recipeID string) ([]dto.ShareResponse, error) {
+	r, err := ownedRecipe(s.DB, userID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []models.RecipeShare
+	if err := s.DB.Where("recipe_id = ?", r.ID).Order("created_at DESC").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	out := make([]dto.ShareResponse, 0, len(shares))
+	for _, sh := range shares {
+		out = append(out, toShareResponse(sh))
+	}
+	return out, nil
+}
+
+// RevokeShare disables a share link. Revoking twice is a no-op.
+func (s *RecipeService) RevokeShare(userID, recipeID, shareID string) error {
+	r, err := ownedRecipe(s.DB, userID, recipeID)
+	if err != nil {
+		return err
+	}
+
+	var share models.RecipeShare
+	if err := s.DB.First(&share, "id = ? AND recipe_id = ?", shareID, r.ID).Error; err != nil {
+		return ErrShareNotFound
+	}
+	if share.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	return s.DB.Model(&share).Update("revoked_at", now).Error
+}
+
+// GetSharedRecipe resolves a share token to its recipe. The link stops
+// working when it is revoked or expired, or when the author makes the recipe
+// private or takes it out of the published state.
+func (s *RecipeService) GetSharedRecipe(token string) (dto.RecipeResponse, error) {
+	var share models.RecipeShare
+	if err := s.DB.First(&share, "token = ?", token).Error; err != nil || !share.Active(time.Now()) {
+		return dto.RecipeResponse{}, ErrShareNotFound
+	}
+
+	var r models.Recipe
+	if err := preloadRecipe(s.DB).First(&r, "id = ?", share.RecipeID).Error; err != nil {
+		return dto.RecipeResponse{}, ErrShareNotFound
+	}
+	if r.Visibility == models.VisibilityPrivate || r.Status != models.RecipeStatusPublished {
+		return dto.RecipeResponse{}, ErrShareNotFound
+	}
+	return toRecipeResponse(r, ""), nil
+}
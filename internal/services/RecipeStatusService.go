@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"gorm.io/gorm"
+)
+
+var RecipeStatuses = []string{
+	models.RecipeStatusDraft,
+	models.RecipeStatusScheduled,
+	models.RecipeStatusPublished,
+	models.RecipeStatusArchived,
+}
+
+func IsValidRecipeStatus(status string) bool {
+	for _, s := range RecipeStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// setRecipeStatus moves a recipe to a new lifecycle state. Scheduling needs a
+// publish time in the future; publishing keeps the first publication date.
+func setRecipeStatus(r *models.Recipe, status string, publishAt *time.Time) error {
+	if status == "" {
+		status = r.Status
+	}
+	if !IsValidRecipeStatus(status) {
+		return errors.New("status must be draft, scheduled, published or archived")
+	}
+
+	now := time.Now()
+	switch status {
+	case models.RecipeStatusScheduled:
+		if publishAt == nil {
+			publishAt = r.PublishAt
+		}
+		if publishAt == nil || !publishAt.After(now) {
+			return errors.New("scheduled recipes need a publish_at in the future")
+		}
+		r.PublishAt = publishAt
+	case models.RecipeStatusPublished:
+		r.PublishAt = nil
+		if r.PublishedAt == nil {
+			r.PublishedAt = &now
+		}
+	default:
+		r.PublishAt = nil
+	}
+
+	r.Status = status
+	return nil
+}
+
+// publicOnly restricts a recipe query to recipes everyone may see and that
+// may appear in listings: published and public.
+func publicOnly(db *gorm.DB) *gorm.DB {
+	return db.Where("recipes.status = ? AND recipes.visibility = ?", models.RecipeStatusPublished, models.VisibilityPublic)
+}
+
+// canView reports whether the user may see the recipe without a share link:
+// published public recipes are visible to everyone, everything else only to
+// its author.
+func canView(r models.Recipe, userID string) bool {
+	if userID != "" && r.UserID.String() == userID {
+		return true
+	}
+	return r.Status == models.RecipeStatusPublished && r.Visibility == models.VisibilityPublic
+}
+
+// PublishDueRecipes publishes every scheduled recipe whose publish time has
+// passed and returns how many were published.
+func PublishDueRecipes(db *gorm.DB) (int64, error) {
+	res := db.Model(&models.Recipe{}).
+		Where("status = ? AND publish_at <= ?", models.RecipeStatusScheduled, time.Now()).
+		Updates(map[string]interface{}{
+			"status":       models.RecipeStatusPublished,
+			"published_at": gorm.Expr("COALESCE(published_at, publish_at)"),
+			"publish_at":   nil,
+			"version":      gorm.Expr("version + 1"),
+		})
+	return res.RowsAffected, res.Error
+}
+
+// StartPublishScheduler runs PublishDueRecipes every interval until the
+// returned stop function is called.
+func StartPublishScheduler(db *gorm.DB, interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				n, err := PublishDueRecipes(db)
+				if err != nil {
+					log.Printf("Failed to publish scheduled recipes: %v", err)
+				} else if n > 0 {
+					log.Printf("Published %d scheduled recipes", n)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// StatusCounts returns the number of the user's recipes in each status.
+func (s *RecipeService) StatusCounts(userID string) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := s.DB.Model(&models.Recipe{}).
+		Select("status, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(RecipeStatuses))
+	for _, status := range RecipeStatuses {
+		counts[status] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"gorm.io/gorm"
+)
+
+const defaultTrashRetentionDays = 30
+
+// TrashRetention is how long deleted recipes stay in the trash before they
+// are purged, configured in days with TRASH_RETENTION_DAYS.
+func TrashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// trashedRecipe loads one of the user's deleted recipes.
+func trashedRecipe(db *gorm.DB, userID, id string) (models.Recipe, error) {
+	var r models.Recipe
+	if err := db.Unscoped().First(&r, "id = ? AND deleted_at IS NOT NULL", id).Error; err != nil {
+		return r, ErrRecipeNotFound
+	}
+	if r.UserID.String() != userID {
+		return r, ErrForbidden
+	}
+	return r, nil
+}
+
+// ListTrash returns the user's deleted recipes, most recently deleted first,
+// with the time each one will be purged.
+func (s *RecipeService) ListTrash(userID string) ([]dto.TrashedRecipeResponse, error) {
+	var recipes []models.Recipe
+	if err := preloadRecipe(s.DB.Unscoped()).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&recipes).Error; err != nil {
+		return nil, err
+	}
+
+	retention := TrashRetention()
+	out := make([]dto.TrashedRecipeResponse, 0, len(recipes))
+	for _, r := range recipes {
+		out = append(out, dto.TrashedRecipeResponse{
+			Recipe:    toRecipeResponse(r, userID),
+			DeletedAt: r.DeletedAt.Time,
+			PurgeAt:   r.DeletedAt.Time.Add(retention),
+		})
+	}
+	return out, nil
+}
+
+// RestoreRecipe takes a recipe out of the trash.
+func (s *RecipeService) RestoreRecipe(userID, id string) (dto.RecipeResponse, error) {
+	var out dto.RecipeResponse
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		r, err := trashedRecipe(tx, userID, id)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&r).UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if r.ForkedFromID != nil {
+			if err := tx.Model(&models.Recipe{}).Where("id = ?", *r.ForkedFromID).
+				UpdateColumn("fork_count", gorm.Expr("fork_count + 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := preloadRecipe(tx).First(&r, "id = ?", r.ID).Error; err != nil {
+			return err
+		}
+		out = toRecipeResponse(r, userID)
+		return nil
+	})
+	return out, err
+}
+
+// PurgeRecipe permanently deletes one of the user's trashed recipes now
+// instead of waiting for the retention period.
+func (s *RecipeService) PurgeRecipe(userID, id string) error {
+	r, err := trashedRecipe(s.DB, userID, id)
+	if err != nil {
+		return err
+	}
+	return s.purge(r)
+}
+
+// purge hard-deletes a recipe with everything attached to it and removes its
+// image and video files once the rows are gone. Only a failure to delete the
+// rows is returned.
+func (s *RecipeService) purge(r models.Recipe) error {
+	var keys []string
+	if err := s.DB.Model(&models.RecipeImage{}).Where("recipe_id = ?", r.ID).Pluck("key", &keys).Error; err != nil {
+		return err
+	}
+	var videos []models.RecipeVideo
+	if err := s.DB.Where("recipe_id = ?", r.ID).Find(&videos).Error; err != nil {
+		return err
+	}
+	for _, v := range videos {
+		keys = append(keys, v.Key, v.PosterKey)
+	}
+	if r.Thumbnail != "" && !slices.Contains(keys, r.Thumbnail) {
+		keys = append(keys, r.Thumbnail)
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&r).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&r).Error
+	})
+	if err != nil {
+		return err
+	}
+	// The recipe is gone, so a file that cannot be removed now is left for
+	// the orphan collector rather than failing the purge.
+	for _, key := range keys {
+		if err := s.DeleteImage(key); err != nil {
+			log.Printf("Failed to remove file %s of purged recipe %s: %v", key, r.ID, err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpiredRecipes hard-deletes every recipe that has been in the trash
+// longer than the retention period and returns how many were removed.
+func (s *RecipeService) PurgeExpiredRecipes(retention time.Duration) (int, error) {
+	var expired []models.Recipe
+	if err := s.DB.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-retention)).
+		Find(&expired).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, r := range expired {
+		if err := s.purge(r); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartTrashPurger runs PurgeExpiredRecipes every interval until the
+// returned stop function is called.
+func StartTrashPurger(db *gorm.DB, interval time.Duration) (stop func()) {
+	service := NewRecipeService(db)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				n, err := service.PurgeExpiredRecipes(TrashRetention())
+				if err != nil {
+					log.Printf("Failed to purge trashed recipes: %v", err)
+				} else if n > 0 {
+					log.Printf("Purged %d trashed recipes", n)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
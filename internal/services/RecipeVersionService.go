@@ -0,0 +1,35 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrVersionMismatch = errors.New("recipe was changed by someone else, reload it and try again")
+
+// lockOwnedRecipe loads one of the user's recipes for update, holding a row
+// lock until the transaction ends. When expectedVersion is set the recipe
+// must still be at that version.
+func lockOwnedRecipe(tx *gorm.DB, userID, id string, expectedVersion *int) (models.Recipe, error) {
+	var r models.Recipe
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&r, "id = ?", id).Error; err != nil {
+		return r, ErrRecipeNotFound
+	}
+	if r.UserID.String() != userID {
+		return r, ErrForbidden
+	}
+	if expectedVersion != nil && r.Version != *expectedVersion {
+		return r, ErrVersionMismatch
+	}
+	return r, nil
+}
+
+// bumpVersion marks a recipe as changed so ETags handed out earlier no
+// longer match.
+func bumpVersion(tx *gorm.DB, recipeID interface{}) error {
+	return tx.Model(&models.Recipe{}).Where("id = ?", recipeID).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
@@ -0,0 +1,462 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrVideoNotFound = errors.New("video not found")
+	ErrVideoURL      = errors.New("video links must be https links to YouTube, Vimeo or an allowed video host")
+)
+
+const (
+	// maxVideoAttempts is how often processing a video is tried before it
+	// is marked failed.
+	maxVideoAttempts = 3
+	videoJobTimeout  = 5 * time.Minute
+	// staleVideoJob is how long a video may stay processing before it is
+	// taken to be abandoned by a stopped server and is picked up again.
+	staleVideoJob = 15 * time.Minute
+	// videoRetryDelay is how long a failed video waits before its second
+	// attempt; the wait doubles with every further attempt.
+	videoRetryDelay = time.Minute
+	// maxPosterDownload caps the poster images fetched from video sites.
+	maxPosterDownload = 10 * 1024 * 1024
+)
+
+// videoProviders are the video sites recipes can link to, with the oEmbed
+// endpoint that describes their videos.
+var videoProviders = map[string]struct{ name, oembed string }{
+	"youtube.com": {"youtube", "https://www.youtube.com/oembed?format=json&url="},
+	"youtu.be":    {"youtube", "https://www.youtube.com/oembed?format=json&url="},
+	"vimeo.com":   {"vimeo", "https://vimeo.com/api/oembed.json?url="},
+}
+
+var videoHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// videoFileHosts are further hosts, listed in VIDEO_URL_HOSTS, that serve
+// video files directly. Their videos are probed like uploads.
+func videoFileHosts() []string {
+	var hosts []string
+	for _, h := range strings.Split(os.Getenv("VIDEO_URL_HOSTS"), ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+func hostMatches(host, allowed string) bool {
+	return host == allowed || strings.HasSuffix(host, "."+allowed)
+}
+
+// externalVideo checks a video link against the allowed hosts and returns
+// it cleaned up with the provider it belongs to, or "" for a file host.
+func externalVideo(raw string) (link, provider string, err error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme != "https" || u.Host == "" || len(u.String()) > 500 {
+		return "", "", ErrVideoURL
+	}
+	host := strings.ToLower(u.Hostname())
+	for h, p := range videoProviders {
+		if hostMatches(host, h) {
+			return u.String(), p.name, nil
+		}
+	}
+	for _, h := range videoFileHosts() {
+		if hostMatches(host, h) {
+			return u.String(), "", nil
+		}
+	}
+	return "", "", ErrVideoURL
+}
+
+func orderedVideos(db *gorm.DB) *gorm.DB {
+	return db.Order("recipe_videos.position ASC")
+}
+
+// toVideoResponses renders videos; private selects signed links for the
+// files of recipes that are not public.
+func toVideoResponses(items []models.RecipeVideo, private bool) []dto.RecipeVideoResponse {
+	out := make([]dto.RecipeVideoResponse, 0, len(items))
+	for _, v := range items {
+		res := dto.RecipeVideoResponse{
+			ID:       v.ID,
+			Source:   "external",
+			URL:      v.ExternalURL,
+			Provider: v.Provider,
+			Title:    v.Title,
+			Position: v.Position,
+			Status:   v.Status,
+		}
+		if v.Key != "" {
+			res.Source = "upload"
+			res.URL = mediaURL(v.Key, private)
+		}
+		if v.Status == models.VideoStatusReady {
+			res.DurationSeconds = v.DurationSeconds
+			res.Width, res.Height = v.Width, v.Height
+			if v.PosterKey != "" {
+				res.PosterURL = mediaURL(v.PosterKey, private)
+				res.PosterVariants = mediaVariants(v.PosterKey, private)
+				res.PosterPlaceholder = toPlaceholder(v.PosterPlaceholder)
+			}
+		}
+		out = append(out, res)
+	}
+	return out
+}
+
+// newVideo attaches a video to a recipe, waiting to be processed. An
+// uploaded video gets its own reference on the file; the returned video
+// carries its key even on error so the caller can release it.
+func newVideo(tx *gorm.DB, userID string, recipeID uuid.UUID, in dto.VideoInput, position int) (models.RecipeVideo, error) {
+	v := models.RecipeVideo{
+		ID:       uuid.New(),
+		RecipeID: recipeID,
+		Title:    truncate(strings.TrimSpace(in.Title), 255),
+		Position: position,
+		Status:   models.VideoStatusPending,
+	}
+	var err error
+	switch {
+	case in.UploadID != "" && in.URL != "":
+		return v, errors.New("set either upload_id or url for a video")
+	case in.UploadID != "":
+		v.Key, _, err = claimUpload(tx, userID, in.UploadID, models.UploadKindVideo)
+	case in.URL != "":
+		v.ExternalURL, v.Provider, err = externalVideo(in.URL)
+	default:
+		return v, errors.New("a video needs upload_id or url")
+	}
+	if err != nil {
+		return v, err
+	}
+	return v, tx.Create(&v).Error
+}
+
+// AddVideo attaches a video to one of the user's recipes. Its duration,
+// size and poster are filled in by the video processor.
+func (s *RecipeService) AddVideo(userID, recipeID string, expectedVersion *int, in dto.VideoInput) (dto.RecipeResponse, error) {
+	var added string
+	res, err := s.editMedia(userID, recipeID, expectedVersion, func(tx *gorm.DB, r models.Recipe) ([]string, error) {
+		var count int64
+		if err := tx.Model(&models.RecipeVideo{}).Where("recipe_id = ?", r.ID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		v, err := newVideo(tx, userID, r.ID, in, int(count)+1)
+		added = v.Key
+		return nil, err
+	})
+	if err != nil {
+		_ = removeStored(added)
+		return res, err
+	}
+	notifyVideoJobs()
+	return res, nil
+}
+
+// RemoveVideo detaches a video from one of the user's recipes and drops its
+// files.
+func (s *RecipeService) RemoveVideo(userID, recipeID, videoID string, expectedVersion *int) (dto.RecipeResponse, error) {
+	return s.editMedia(userID, recipeID, expectedVersion, func(tx *gorm.DB, r models.Recipe) ([]string, error) {
+		var v models.RecipeVideo
+		if err := tx.First(&v, "id = ? AND recipe_id = ?", videoID, r.ID).Error; err != nil {
+			return nil, ErrVideoNotFound
+		}
+		if err := tx.Delete(&v).Error; err != nil {
+			return nil, err
+		}
+		var ids []uuid.UUID
+		if err := orderedVideos(tx.Model(&models.RecipeVideo{})).Where("recipe_id = ?", r.ID).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		if err := writeOrder(tx, &models.RecipeVideo{}, "position", ids, nil); err != nil {
+			return nil, err
+		}
+		return []string{v.Key, v.PosterKey}, nil
+	})
+}
+
+// videoJobs wakes the video processor when videos are added, so they do not
+// wait for its next tick.
+var videoJobs = make(chan struct{}, 1)
+
+func notifyVideoJobs() {
+	select {
+	case videoJobs <- struct{}{}:
+	default:
+	}
+}
+
+// claimVideo marks the next video waiting to be processed as processing and
+// returns it. Locked rows are skipped so several servers can share the work.
+func claimVideo(db *gorm.DB) (models.RecipeVideo, bool, error) {
+	var v models.RecipeVideo
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR (status = ? AND updated_at < ? AND attempts < ?)",
+				models.VideoStatusPending, now, models.VideoStatusProcessing, now.Add(-staleVideoJob), maxVideoAttempts).
+			Order("created_at ASC").First(&v).Error; err != nil {
+			return err
+		}
+		v.Status = models.VideoStatusProcessing
+		v.Attempts++
+		return tx.Save(&v).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return v, false, nil
+	}
+	return v, err == nil, err
+}
+
+// failAbandonedVideos marks videos failed that were left processing by a
+// stopped server on their last attempt, so they are not retried forever.
+func failAbandonedVideos(db *gorm.DB) error {
+	return db.Model(&models.RecipeVideo{}).
+		Where("status = ? AND updated_at < ? AND attempts >= ?",
+			models.VideoStatusProcessing, time.Now().Add(-staleVideoJob), maxVideoAttempts).
+		Updates(map[string]interface{}{"status": models.VideoStatusFailed, "error": "processing did not finish"}).Error
+}
+
+// ProcessPendingVideos processes waiting videos until none is due and
+// returns how many were handled. Failed videos wait before they are tried
+// again, so a retry does not follow right after the failure.
+func (s *RecipeService) ProcessPendingVideos() (int, error) {
+	if err := failAbandonedVideos(s.DB); err != nil {
+		return 0, err
+	}
+	handled := 0
+	for {
+		v, ok, err := claimVideo(s.DB)
+		if err != nil || !ok {
+			return handled, err
+		}
+		info, posterKey, poster, jobErr := processVideo(v)
+		if err := s.finishVideo(v, info, posterKey, poster, jobErr); err != nil {
+			return handled, err
+		}
+		handled++
+	}
+}
+
+// finishVideo stores the outcome of processing a video and bumps the
+// recipe's version so cached copies pick it up. A failed attempt is retried
+// after a growing delay until maxVideoAttempts. The poster is dropped when the video was removed
+// in the meantime.
+func (s *RecipeService) finishVideo(v models.RecipeVideo, info utils.VideoInfo, posterKey string, poster models.ImagePlaceholder, jobErr error) error {
+	updates := map[string]interface{}{"status": models.VideoStatusReady, "error": "", "next_attempt_at": nil}
+	if jobErr == nil {
+		updates["duration_seconds"] = info.DurationSeconds
+		updates["width"] = info.Width
+		updates["height"] = info.Height
+		updates["poster_key"] = posterKey
+		updates["poster_blur_hash"] = poster.BlurHash
+		updates["poster_color"] = poster.Color
+	} else {
+		log.Printf("Processing video %s failed (attempt %d): %v", v.ID, v.Attempts, jobErr)
+		updates["error"] = truncate(jobErr.Error(), 500)
+		updates["status"] = models.VideoStatusPending
+		updates["next_attempt_at"] = time.Now().Add(videoRetryDelay << (v.Attempts - 1))
+		if v.Attempts >= maxVideoAttempts {
+			updates["status"] = models.VideoStatusFailed
+		}
+	}
+
+	applied := false
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.RecipeVideo{}).
+			Where("id = ? AND status = ?", v.ID, models.VideoStatusProcessing).
+			Updates(updates)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		applied = true
+		return bumpVersion(tx, v.RecipeID)
+	})
+	if err != nil || !applied {
+		_ = removeStored(posterKey)
+	}
+	return err
+}
+
+// processVideo reads the duration and size of a video and stores a poster
+// frame. Uploaded videos and files on allowed hosts are probed with ffprobe
+// and ffmpeg; videos on video sites are described by their oEmbed data.
+func processVideo(v models.RecipeVideo) (info utils.VideoInfo, posterKey string, poster models.ImagePlaceholder, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), videoJobTimeout)
+	defer cancel()
+
+	switch {
+	case v.Key != "":
+		input, cleanup, err := downloadStored(ctx, v.Key)
+		if err != nil {
+			return info, "", poster, err
+		}
+		defer cleanup()
+		return probeVideo(ctx, input)
+	case v.Provider != "":
+		return describeEmbed(ctx, v)
+	default:
+		return probeVideo(ctx, v.ExternalURL)
+	}
+}
+
+// downloadStored copies a stored file to a temporary file for the ffmpeg
+// tools, which cannot read from every storage backend.
+func downloadStored(ctx context.Context, key string) (string, func(), error) {
+	src, err := storage.Default.Open(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp("", "video-*"+path.Ext(key))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tmp.Name(), cleanup, nil
+}
+
+func probeVideo(ctx context.Context, input string) (utils.VideoInfo, string, models.ImagePlaceholder, error) {
+	info, err := utils.ProbeVideo(ctx, input)
+	if err != nil {
+		return info, "", models.ImagePlaceholder{}, err
+	}
+	frame, err := utils.ExtractPoster(ctx, input, utils.PosterAt(info.DurationSeconds))
+	if err != nil {
+		return info, "", models.ImagePlaceholder{}, err
+	}
+	posterKey, poster, err := storeImage(bytes.NewReader(frame))
+	return info, posterKey, poster, err
+}
+
+type oEmbedVideo struct {
+	Width        int     `json:"width"`
+	Height       int     `json:"height"`
+	Duration     float64 `json:"duration"`
+	ThumbnailURL string  `json:"thumbnail_url"`
+}
+
+// describeEmbed asks a video site for the size, duration and thumbnail of
+// a video. Not every site reports a duration.
+func describeEmbed(ctx context.Context, v models.RecipeVideo) (utils.VideoInfo, string, models.ImagePlaceholder, error) {
+	var provider string
+	for _, p := range videoProviders {
+		if p.name == v.Provider {
+			provider = p.oembed
+		}
+	}
+	if provider == "" {
+		return utils.VideoInfo{}, "", models.ImagePlaceholder{}, fmt.Errorf("unknown video provider %q", v.Provider)
+	}
+
+	body, err := fetch(ctx, provider+url.QueryEscape(v.ExternalURL), 1024*1024)
+	if err != nil {
+		return utils.VideoInfo{}, "", models.ImagePlaceholder{}, err
+	}
+	var embed oEmbedVideo
+	if err := json.Unmarshal(body, &embed); err != nil {
+		return utils.VideoInfo{}, "", models.ImagePlaceholder{}, fmt.Errorf("unreadable oEmbed response: %w", err)
+	}
+	info := utils.VideoInfo{DurationSeconds: embed.Duration, Width: embed.Width, Height: embed.Height}
+	if embed.ThumbnailURL == "" || !strings.HasPrefix(embed.ThumbnailURL, "https://") {
+		return info, "", models.ImagePlaceholder{}, nil
+	}
+
+	thumbnail, err := fetch(ctx, embed.ThumbnailURL, maxPosterDownload)
+	if err != nil {
+		return info, "", models.ImagePlaceholder{}, err
+	}
+	posterKey, poster, err := storeImage(bytes.NewReader(thumbnail))
+	return info, posterKey, poster, err
+}
+
+// fetch downloads a document of at most limit bytes.
+func fetch(ctx context.Context, link string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := videoHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", link, res.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("GET %s: response is larger than %d bytes", link, limit)
+	}
+	return body, nil
+}
+
+// StartVideoProcessor processes waiting videos every interval, and as soon
+// as videos are added, until the returned stop function is called.
+func StartVideoProcessor(db *gorm.DB, interval time.Duration) (stop func()) {
+	service := NewRecipeService(db)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	run := func() {
+		n, err := service.ProcessPendingVideos()
+		if err != nil {
+			log.Printf("Failed to process videos: %v", err)
+		} else if n > 0 {
+			log.Printf("Processed %d videos", n)
+		}
+	}
+
+	go func() {
+		run()
+		for {
+			select {
+			case <-ticker.C:
+				run()
+			case <-videoJobs:
+				run()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
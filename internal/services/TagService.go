@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxTagsPerRecipe = 10
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("a tag with this name already exists, merge the tags instead")
+)
+
+type TagService struct {
+	DB *gorm.DB
+}
+
+func NewTagService(db *gorm.DB) *TagService {
+	return &TagService{DB: db}
+}
+
+var tagSpaces = regexp.MustCompile(`\s+`)
+
+// NormalizeTagName trims a free-text tag for display: "  #Pedas   Manis " → "Pedas Manis".
+func NormalizeTagName(name string) string {
+	name = strings.TrimLeft(strings.TrimSpace(name), "#")
+	return tagSpaces.ReplaceAllString(strings.TrimSpace(name), " ")
+}
+
+// TagSlug turns a tag name into its unique key, so "Pedas Manis" and
+// "pedas-manis " end up as the same tag.
+func TagSlug(name string) string {
+	return utils.Slugify(NormalizeTagName(name))
+}
+
+func toTagResponses(tags []models.Tag) []dto.TagResponse {
+	out := make([]dto.TagResponse, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, dto.TagResponse{ID: t.ID, Name: t.Name, Slug: t.Slug})
+	}
+	return out
+}
+
+// findOrCreateTags resolves author-supplied tag names to tag rows, reusing
+// existing tags with the same slug and creating the rest.
+func findOrCreateTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	seen := map[string]bool{}
+	var unique []string
+	for _, name := range names {
+		slug := TagSlug(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		if len(slug) > 50 {
+			return nil, errors.New("tag " + name + " is too long")
+		}
+		seen[slug] = true
+		unique = append(unique, name)
+	}
+	if len(unique) > maxTagsPerRecipe {
+		return nil, errors.New("a recipe can have at most 10 tags")
+	}
+
+	tags := make([]models.Tag, 0, len(unique))
+	for _, name := range unique {
+		tag := models.Tag{Name: NormalizeTagName(name), Slug: TagSlug(name)}
+		if err := tx.Where(models.Tag{Slug: tag.Slug}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// setRecipeTags replaces the tags of a recipe with the given names.
+func setRecipeTags(tx *gorm.DB, recipe *models.Recipe, names []string) error {
+	tags, err := findOrCreateTags(tx, names)
+	if err != nil {
+		return err
+	}
+	return tx.Model(recipe).Association("Tags").Replace(tags)
+}
+
+// applyTagFilter keeps recipes tagged with every requested tag (mode "all")
+// or with at least one of them (mode "any").
+func applyTagFilter(db *gorm.DB, filter dto.RecipeFilter) *gorm.DB {
+	if len(filter.Tags) == 0 {
+		return db
+	}
+
+	// A tag listed twice must not raise the count mode "all" asks for.
+	slugs := make([]string, 0, len(filter.Tags))
+	for _, t := range filter.Tags {
+		if slug := TagSlug(t); slug != "" && !slices.Contains(slugs, slug) {
+			slugs = append(slugs, slug)
+		}
+	}
+
+	sub := db.Session(&gorm.Session{NewDB: true}).
+		Table("recipe_tags").
+		Select("recipe_tags.recipe_id").
+		Joins("JOIN tags ON tags.id = recipe_tags.tag_id").
+		Where("tags.slug IN ?", slugs)
+	if filter.TagMode != "any" {
+		sub = sub.Group("recipe_tags.recipe_id").Having("COUNT(DISTINCT tags.slug) = ?", len(slugs))
+	}
+	return db.Where("recipes.id IN (?)", sub)
+}
+
+// ListTags returns tags with the number of published public recipes using
+// them, most used first, optionally filtered by a name prefix.
+func (s *TagService) ListTags(q string) ([]dto.TagCountResponse, error) {
+	var out []dto.TagCountResponse
+	query := s.DB.Table("tags").
+		Select("tags.id, tags.name, tags.slug, COUNT(recipes.id) AS recipe_count").
+		Joins("LEFT JOIN recipe_tags ON recipe_tags.tag_id = tags.id").
+		Joins("LEFT JOIN recipes ON recipes.id = recipe_tags.recipe_id AND recipes.deleted_at IS NULL AND recipes.status = ? AND recipes.visibility = ?",
+			models.RecipeStatusPublished, models.VisibilityPublic).
+		Group("tags.id, tags.name, tags.slug").
+		Order("recipe_count DESC, tags.name ASC")
+	if slug := TagSlug(q); slug != "" {
+		query = query.Where("tags.slug LIKE ?", slug+"%")
+	}
+	if err := query.Scan(&out).Error; err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = []dto.TagCountResponse{}
+	}
+	return out, nil
+}
+
+func (s *TagService) CreateTag(name string) (dto.TagResponse, error) {
+	slug := TagSlug(name)
+	if slug == "" {
+		return dto.TagResponse{}, errors.New("tag name is required")
+	}
+
+	var existing models.Tag
+	if err := s.DB.Where("slug = ?", slug).First(&existing).Error; err == nil {
+		return dto.TagResponse{}, ErrTagExists
+	}
+
+	tag := models.Tag{Name: NormalizeTagName(name), Slug: slug}
+	if err := s.DB.Create(&tag).Error; err != nil {
+		return dto.TagResponse{}, err
+	}
+	return toTagResponses([]models.Tag{tag})[0], nil
+}
+
+func (s *TagService) UpdateTag(id, name string) (dto.TagResponse, error) {
+	var tag models.Tag
+	if err := s.DB.First(&tag, "id = ?", id).Error; err != nil {
+		return dto.TagResponse{}, ErrTagNotFound
+	}
+
+	slug := TagSlug(name)
+	if slug == "" {
+		return dto.TagResponse{}, errors.New("tag name is required")
+	}
+	var existing models.Tag
+	if err := s.DB.Where("slug = ? AND id <> ?", slug, tag.ID).First(&existing).Error; err == nil {
+		return dto.TagResponse{}, ErrTagExists
+	}
+
+	tag.Name = NormalizeTagName(name)
+	tag.Slug = slug
+	if err := s.DB.Save(&tag).Error; err != nil {
+		return dto.TagResponse{}, err
+	}
+	return toTagResponses([]models.Tag{tag})[0], nil
+}
+
+func (s *TagService) DeleteTag(id string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.First(&tag, "id = ?", id).Error; err != nil {
+			return ErrTagNotFound
+		}
+		if err := tx.Exec("DELETE FROM recipe_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+}
+
+// MergeTags moves every recipe from the source tag onto the target tag and
+// deletes the source, e.g. to fold "makanan" into "food".
+func (s *TagService) MergeTags(sourceID string, targetID uuid.UUID) (dto.TagResponse, error) {
+	var target models.Tag
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var source models.Tag
+		if err := tx.First(&source, "id = ?", sourceID).Error; err != nil {
+			return ErrTagNotFound
+		}
+		if err := tx.First(&target, "id = ?", targetID).Error; err != nil {
+			return ErrTagNotFound
+		}
+		if source.ID == target.ID {
+			return errors.New("cannot merge a tag into itself")
+		}
+
+		if err := tx.Exec(`INSERT INTO recipe_tags (recipe_id, tag_id)
+			SELECT recipe_id, ? FROM recipe_tags
+			WHERE tag_id = ? AND recipe_id NOT IN (SELECT recipe_id FROM recipe_tags WHERE tag_id = ?)`,
+			target.ID, source.ID, target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM recipe_tags WHERE tag_id = ?", source.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&source).Error
+	})
+	if err != nil {
+		return dto.TagResponse{}, err
+	}
+	return toTagResponses([]models.Tag{target})[0], nil
+}
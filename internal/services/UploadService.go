@@ -0,0 +1,534 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/bayuTri-Code/BE-Recipe/internal/DTO"
+	"github.com/bayuTri-Code/BE-Recipe/internal/models"
+	"github.com/bayuTri-Code/BE-Recipe/internal/storage"
+	"github.com/bayuTri-Code/BE-Recipe/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUploadNotFound   = errors.New("upload not found")
+	ErrUploadOffset     = errors.New("Upload-Offset does not match the size received so far")
+	ErrUploadTooLarge   = errors.New("upload exceeds the size allowed for its kind")
+	ErrUploadType       = errors.New("file type is not allowed for this kind of upload")
+	ErrUploadIncomplete = errors.New("upload is not complete")
+	ErrUploadBusy       = errors.New("upload is being written by another request")
+)
+
+// uploadChunkPrefix holds the chunks of unfinished uploads under
+// "uploads/<id>/<offset>". A PATCH is stored in parts of at most
+// uploadPartSize bytes, so the parts that arrived before a connection drops
+// are kept.
+const (
+	uploadChunkPrefix = "uploads"
+	uploadPartSize    = 8 << 20
+	// uploadClaimTimeout is how long a PATCH may go without storing a part
+	// before another request may take over the upload.
+	uploadClaimTimeout = 5 * time.Minute
+)
+
+const defaultUploadExpiryHours = 24
+
+// UploadLimit is what an upload of one kind may be.
+type UploadLimit struct {
+	MaxSize int64
+	Types   []string
+}
+
+// UploadLimits returns the limits per upload kind. The maximum sizes are
+// configured in megabytes with UPLOAD_MAX_IMAGE_MB and UPLOAD_MAX_VIDEO_MB.
+func UploadLimits() map[string]UploadLimit {
+	return map[string]UploadLimit{
+		models.UploadKindImage: {
+			MaxSize: envMegabytes("UPLOAD_MAX_IMAGE_MB", 20),
+			Types:   []string{"image/jpeg", "image/png", "image/webp"},
+		},
+		models.UploadKindVideo: {
+			MaxSize: envMegabytes("UPLOAD_MAX_VIDEO_MB", 100),
+			Types:   []string{"video/mp4", "video/quicktime", "video/webm"},
+		},
+	}
+}
+
+func envMegabytes(name string, fallback int64) int64 {
+	mb, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil || mb <= 0 {
+		mb = fallback
+	}
+	return mb * 1024 * 1024
+}
+
+// MaxUploadSize is the largest upload of any kind.
+func MaxUploadSize() int64 {
+	var largest int64
+	for _, l := range UploadLimits() {
+		largest = max(largest, l.MaxSize)
+	}
+	return largest
+}
+
+// UploadExpiry is how long an upload is kept after its last chunk,
+// configured in hours with UPLOAD_EXPIRY_HOURS. A finished upload is kept
+// as long, so its file can still be attached.
+func UploadExpiry() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("UPLOAD_EXPIRY_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = defaultUploadExpiryHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+type UploadService struct {
+	DB *gorm.DB
+}
+
+func NewUploadService(db *gorm.DB) *UploadService {
+	return &UploadService{DB: db}
+}
+
+func toUploadResponse(u models.Upload) dto.UploadResponse {
+	res := dto.UploadResponse{
+		ID:          u.ID,
+		Kind:        u.Kind,
+		Filename:    u.Filename,
+		ContentType: u.ContentType,
+		Length:      u.Length,
+		Offset:      u.Offset,
+		Complete:    u.Key != "",
+		ExpiresAt:   u.ExpiresAt,
+	}
+	// The file is not attached to anything yet, so only its owner gets a
+	// link.
+	if u.Key != "" {
+		res.URL = mediaURL(u.Key, true)
+		if u.Kind == models.UploadKindImage {
+			res.Variants = mediaVariants(u.Key, true)
+			res.Placeholder = toPlaceholder(u.Placeholder)
+		}
+	}
+	return res
+}
+
+// CreateUpload starts a resumable upload of length bytes. meta holds the
+// tus metadata: "kind" is "image" or "video" and defaults from "filetype";
+// "filename" is kept for display.
+func (s *UploadService) CreateUpload(userID string, length int64, meta map[string]string) (models.Upload, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return models.Upload{}, errors.New("invalid user ID")
+	}
+
+	fileType := strings.ToLower(meta["filetype"])
+	kind := meta["kind"]
+	if kind == "" {
+		kind, _, _ = strings.Cut(fileType, "/")
+	}
+	limit, ok := UploadLimits()[kind]
+	if !ok {
+		return models.Upload{}, errors.New("kind must be image or video")
+	}
+	if fileType != "" && !slices.Contains(limit.Types, fileType) {
+		return models.Upload{}, ErrUploadType
+	}
+	if length <= 0 {
+		return models.Upload{}, errors.New("Upload-Length must be positive")
+	}
+	if length > limit.MaxSize {
+		return models.Upload{}, ErrUploadTooLarge
+	}
+
+	u := models.Upload{
+		UserID:      uid,
+		Kind:        kind,
+		Filename:    truncate(meta["filename"], 255),
+		ContentType: fileType,
+		Length:      length,
+		ExpiresAt:   time.Now().Add(UploadExpiry()),
+	}
+	err = s.DB.Create(&u).Error
+	return u, err
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// GetUpload returns one of the user's uploads.
+func (s *UploadService) GetUpload(userID, id string) (models.Upload, error) {
+	var u models.Upload
+	if err := s.DB.First(&u, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return u, ErrUploadNotFound
+	}
+	return u, nil
+}
+
+// UploadResponse describes one of the user's uploads.
+func (s *UploadService) UploadResponse(userID, id string) (dto.UploadResponse, error) {
+	u, err := s.GetUpload(userID, id)
+	if err != nil {
+		return dto.UploadResponse{}, err
+	}
+	return toUploadResponse(u), nil
+}
+
+// WriteChunk appends the bytes of body to an upload that has received
+// exactly offset bytes so far. size is the length of body when known, or -1.
+// When body breaks off, the bytes received until then are kept and the
+// client resumes from the returned offset. The chunk that completes the
+// upload also finishes it: the file goes through the image pipeline or is
+// stored as it is.
+//
+// The body is stored outside any transaction: the request claims the upload
+// at offset, stores its parts and then moves the offset on, provided it
+// still holds the claim.
+func (s *UploadService) WriteChunk(userID, id string, offset int64, body io.Reader, size int64) (models.Upload, error) {
+	u, err := s.GetUpload(userID, id)
+	if err != nil {
+		return u, err
+	}
+	if offset != u.Offset {
+		return u, ErrUploadOffset
+	}
+	remaining := u.Length - u.Offset
+	if size > remaining {
+		return u, ErrUploadTooLarge
+	}
+	if remaining == 0 {
+		if u.Key != "" {
+			return u, nil
+		}
+		return s.finish(u)
+	}
+
+	claim := uuid.New()
+	now := time.Now()
+	res := s.DB.Model(&models.Upload{}).
+		Where("id = ? AND \"offset\" = ? AND (claim_id IS NULL OR claimed_at < ?)", u.ID, offset, now.Add(-uploadClaimTimeout)).
+		Updates(map[string]interface{}{"claim_id": claim, "claimed_at": now})
+	if res.Error != nil {
+		return u, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if err := s.DB.First(&u, "id = ?", u.ID).Error; err == nil && u.Offset != offset {
+			return u, ErrUploadOffset
+		}
+		return u, ErrUploadBusy
+	}
+	claimed := s.DB.Model(&models.Upload{}).Where("id = ? AND claim_id = ?", u.ID, claim).Session(&gorm.Session{})
+
+	storeErr := writeParts(u.ID, &u.Offset, &u.Chunks, io.LimitReader(body, remaining), func() error {
+		res := claimed.Update("claimed_at", time.Now())
+		if res.Error == nil && res.RowsAffected == 0 {
+			return ErrUploadBusy
+		}
+		return res.Error
+	})
+
+	u.ExpiresAt = time.Now().Add(UploadExpiry())
+	res = claimed.Where("\"offset\" = ?", offset).Updates(map[string]interface{}{
+		"offset":     u.Offset,
+		"chunks":     u.Chunks,
+		"expires_at": u.ExpiresAt,
+		"claim_id":   nil,
+		"claimed_at": nil,
+	})
+	if res.Error != nil {
+		return u, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return u, ErrUploadBusy
+	}
+	if storeErr != nil || u.Offset < u.Length {
+		return u, storeErr
+	}
+	return s.finish(u)
+}
+
+// writeParts stores body as chunks of at most uploadPartSize bytes starting
+// at *offset, advancing *offset and *chunks for every chunk stored, and
+// calls stored after each. A body that breaks off ends the chunk like its
+// end would; only failing to store a chunk, or an error from stored, is an
+// error.
+func writeParts(id uuid.UUID, offset *int64, chunks *int, body io.Reader, stored func() error) error {
+	buf := make([]byte, uploadPartSize)
+	for {
+		n, readErr := io.ReadFull(body, buf)
+		if n > 0 {
+			key := fmt.Sprintf("%s/%s/%020d", uploadChunkPrefix, id, *offset)
+			if err := storage.Default.Put(context.Background(), key, bytes.NewReader(buf[:n]), int64(n), "application/octet-stream"); err != nil {
+				return err
+			}
+			*offset += int64(n)
+			*chunks++
+			if err := stored(); err != nil {
+				return err
+			}
+		}
+		if readErr != nil {
+			if !errors.Is(readErr, io.EOF) && !errors.Is(readErr, io.ErrUnexpectedEOF) {
+				log.Printf("Upload %s broke off at byte %d: %v", id, *offset, readErr)
+			}
+			return nil
+		}
+	}
+}
+
+// uploadChunks lists the chunk keys of an upload in order and checks that
+// they add up to the whole file.
+func uploadChunks(u models.Upload) ([]string, error) {
+	var chunks []storage.Object
+	err := storage.Default.List(context.Background(), fmt.Sprintf("%s/%s/", uploadChunkPrefix, u.ID), func(o storage.Object) error {
+		chunks = append(chunks, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Key < chunks[j].Key })
+
+	keys := make([]string, 0, len(chunks))
+	var total int64
+	for _, c := range chunks {
+		at, err := strconv.ParseInt(c.Key[strings.LastIndex(c.Key, "/")+1:], 10, 64)
+		if err != nil || at != total {
+			return nil, fmt.Errorf("upload %s has a gap at byte %d", u.ID, total)
+		}
+		total += c.Size
+		keys = append(keys, c.Key)
+	}
+	if total != u.Length {
+		return nil, fmt.Errorf("upload %s has %d of %d bytes", u.ID, total, u.Length)
+	}
+	return keys, nil
+}
+
+// chunkReader reads the chunks of an upload one after another, opening each
+// only when it is reached.
+type chunkReader struct {
+	keys []string
+	cur  io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			f, err := storage.Default.Open(context.Background(), r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			r.cur, r.keys = f, r.keys[1:]
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+	return nil
+}
+
+// finish stores the file of a complete upload and drops its chunks. A file
+// that does not match its kind is rejected and the upload is removed.
+func (s *UploadService) finish(u models.Upload) (models.Upload, error) {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, "id = ?", u.ID).Error; err != nil {
+			return ErrUploadNotFound
+		}
+		if u.Key != "" {
+			return nil
+		}
+
+		keys, err := uploadChunks(u)
+		if err != nil {
+			return err
+		}
+		open := func() (io.ReadCloser, error) {
+			return &chunkReader{keys: keys}, nil
+		}
+
+		key, contentType, placeholder, err := storeUploadedFile(u, open)
+		if err != nil {
+			return err
+		}
+		u.Key = key
+		u.ContentType = contentType
+		u.Placeholder = placeholder
+		if err := tx.Save(&u).Error; err != nil {
+			_ = removeStored(key)
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, ErrUploadType) || errors.Is(err, utils.ErrInvalidImage) {
+		if rmErr := s.remove(u); rmErr != nil {
+			log.Printf("Failed to remove rejected upload %s: %v", u.ID, rmErr)
+		}
+		return u, err
+	}
+	if err != nil {
+		return u, err
+	}
+	deleteChunks(u)
+	return u, nil
+}
+
+// storeUploadedFile hands a finished upload to storage: images go through
+// the image pipeline, videos are kept as they are. Only images have a
+// placeholder.
+func storeUploadedFile(u models.Upload, open func() (io.ReadCloser, error)) (key, contentType string, placeholder models.ImagePlaceholder, err error) {
+	if u.Kind == models.UploadKindImage {
+		src, err := open()
+		if err != nil {
+			return "", "", placeholder, err
+		}
+		defer src.Close()
+		key, placeholder, err := storeImage(src)
+		return key, "image/jpeg", placeholder, err
+	}
+
+	src, err := open()
+	if err != nil {
+		return "", "", placeholder, err
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	src.Close()
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", "", placeholder, err
+	}
+	contentType, ext := utils.SniffVideo(head[:n])
+	if !slices.Contains(UploadLimits()[u.Kind].Types, contentType) {
+		return "", "", placeholder, ErrUploadType
+	}
+	key, err = storeFile(open, u.Length, ext, contentType)
+	return key, contentType, placeholder, err
+}
+
+func deleteChunks(u models.Upload) {
+	ctx := context.Background()
+	err := storage.Default.List(ctx, fmt.Sprintf("%s/%s/", uploadChunkPrefix, u.ID), func(o storage.Object) error {
+		return storage.Default.Delete(ctx, o.Key)
+	})
+	if err != nil {
+		log.Printf("Failed to delete chunks of upload %s: %v", u.ID, err)
+	}
+}
+
+// remove deletes an upload, its chunks and its reference on the file.
+func (s *UploadService) remove(u models.Upload) error {
+	if err := s.DB.Delete(&models.Upload{}, "id = ?", u.ID).Error; err != nil {
+		return err
+	}
+	deleteChunks(u)
+	return removeStored(u.Key)
+}
+
+// DeleteUpload cancels one of the user's uploads. Files already attached to
+// recipes are kept.
+func (s *UploadService) DeleteUpload(userID, id string) error {
+	u, err := s.GetUpload(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.remove(u)
+}
+
+// PurgeExpiredUploads removes every upload past its expiry and returns how
+// many were removed. An upload that cannot be removed is logged and tried
+// again on the next run.
+func (s *UploadService) PurgeExpiredUploads() (int, error) {
+	var expired []models.Upload
+	if err := s.DB.Where("expires_at < ?", time.Now()).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, u := range expired {
+		if err := s.remove(u); err != nil {
+			log.Printf("Failed to purge expired upload %s: %v", u.ID, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartUploadPurger runs PurgeExpiredUploads every interval until the
+// returned stop function is called.
+func StartUploadPurger(db *gorm.DB, interval time.Duration) (stop func()) {
+	service := NewUploadService(db)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				n, err := service.PurgeExpiredUploads()
+				if err != nil {
+					log.Printf("Failed to purge expired uploads: %v", err)
+				} else if n > 0 {
+					log.Printf("Purged %d expired uploads", n)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// claimUpload takes a new reference on the file of one of the user's
+// finished uploads of the given kind and returns its key and placeholder.
+func claimUpload(db *gorm.DB, userID, uploadID, kind string) (string, models.ImagePlaceholder, error) {
+	var u models.Upload
+	if err := db.First(&u, "id = ? AND user_id = ?", uploadID, userID).Error; err != nil {
+		return "", models.ImagePlaceholder{}, ErrUploadNotFound
+	}
+	if u.Key == "" {
+		return "", models.ImagePlaceholder{}, ErrUploadIncomplete
+	}
+	if u.Kind != kind {
+		return "", models.ImagePlaceholder{}, ErrUploadType
+	}
+	key, err := copyStored(u.Key, "")
+	if err == nil && key == "" {
+		return "", models.ImagePlaceholder{}, ErrUploadNotFound
+	}
+	return key, u.Placeholder, err
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func readObject(t *testing.T, s Storage, key string) string {
+	t.Helper()
+	f, err := s.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Open(%q): %v", key, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	l := NewLocal(root, "http://api.test/media")
+
+	if err := l.Put(ctx, "content/a.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, l, "content/a.txt"); got != "hello" {
+		t.Errorf("read %q, want %q", got, "hello")
+	}
+
+	// Put replaces an existing object, also from a body of unknown size.
+	if err := l.Put(ctx, "content/a.txt", strings.NewReader("hello again"), -1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, l, "content/a.txt"); got != "hello again" {
+		t.Errorf("read %q after replacing", got)
+	}
+
+	if err := l.Copy(ctx, "content/a.txt", "storage/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, l, "storage/b.txt"); got != "hello again" {
+		t.Errorf("copy holds %q", got)
+	}
+	if err := l.Copy(ctx, "content/missing.txt", "storage/c.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Copy of a missing object = %v, want ErrNotFound", err)
+	}
+
+	var keys []string
+	err := l.List(ctx, "content/", func(o Object) error {
+		keys = append(keys, o.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "content/a.txt" {
+		t.Errorf("List(content/) = %v", keys)
+	}
+	if err := l.List(ctx, "nothing/", func(Object) error { return errors.New("called") }); err != nil {
+		t.Errorf("List of a missing prefix = %v", err)
+	}
+
+	if got := l.URL("content/a.txt"); got != "http://api.test/media/content/a.txt" {
+		t.Errorf("URL = %q", got)
+	}
+
+	if err := l.Delete(ctx, "content/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Open(ctx, "content/a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, "content/a.txt"); err != nil {
+		t.Errorf("deleting a missing object = %v", err)
+	}
+}
+
+func TestLocalRejectsKeysOutsideRoot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	l := NewLocal(filepath.Join(dir, "root"), "")
+
+	if err := l.Put(ctx, "../escaped.txt", strings.NewReader("x"), 1, ""); err == nil {
+		t.Error("Put accepted a key outside the root")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("a file was written outside the root")
+	}
+	if _, err := l.Open(ctx, "/etc/passwd"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Open of an absolute key = %v, want a key error", err)
+	}
+}
@@ -0,0 +1,381 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unsignedPayload lets uploads stream without hashing the body first.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+var emptyPayloadHash = hex.EncodeToString(sha256.New().Sum(nil))
+
+type S3Config struct {
+	// Endpoint is the base URL of an S3-compatible server, such as
+	// "http://localhost:9000" for MinIO. Objects are then addressed
+	// path-style. When empty, AWS S3 in Region is used.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PublicURL is where clients fetch the objects of public recipes and
+	// profiles, usually the API's media handler, which checks that the
+	// object is public. It must not expose the bucket itself: objects of
+	// other recipes share it and are only handed out through PresignGet.
+	PublicURL string
+}
+
+// S3 stores objects in an S3-compatible bucket. Requests are signed with
+// AWS Signature Version 4.
+type S3 struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("S3 storage needs S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	endpoint := strings.TrimRight(cfg.Endpoint, "/") + "/" + cfg.Bucket
+	if cfg.Endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", cfg.Bucket, cfg.Region)
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.PublicURL == "" {
+		return nil, errors.New("S3 storage needs a public URL for its objects")
+	}
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+
+	return &S3{cfg: cfg, base: base, client: &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u := *s.base
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + key
+	return &u, nil
+}
+
+// do signs and sends a request for an object and returns the response when
+// its status is 2xx. A 404 yields ErrNotFound.
+func (s *S3) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, method, u, body, size, header)
+}
+
+func (s *S3) send(ctx context.Context, method string, u *url.URL, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		payloadHash = unsignedPayload
+		if size >= 0 {
+			req.ContentLength = size
+		}
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return nil, fmt.Errorf("s3 %s %s: %s: %s", method, u.Path, res.Status, strings.TrimSpace(string(msg)))
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	// S3 needs the length up front; buffer bodies of unknown size.
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	res, err := s.do(ctx, http.MethodPut, key, r, size, header)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *S3) Copy(ctx context.Context, from, to string) error {
+	from, err := CleanKey(from)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", uriEncode("/"+s.cfg.Bucket+"/"+from, false))
+	res, err := s.do(ctx, http.MethodPut, to, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	// A failed copy can still answer 200 with an error document.
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if err != nil {
+		return err
+	}
+	if strings.Contains(string(body), "<Error>") {
+		if strings.Contains(string(body), "NoSuchKey") {
+			return ErrNotFound
+		}
+		return fmt.Errorf("s3 copy %s to %s: %s", from, to, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *S3) URL(key string) string {
+	return s.cfg.PublicURL + "/" + uriEncode(key, false)
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List pages through ListObjectsV2.
+func (s *S3) List(ctx context.Context, prefix string, fn func(Object) error) error {
+	token := ""
+	for {
+		u := *s.base
+		u.Path = strings.TrimRight(u.Path, "/") + "/"
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = q.Encode()
+
+		res, err := s.send(ctx, http.MethodGet, &u, nil, 0, nil)
+		if err != nil {
+			return err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("s3 list %s: %w", prefix, err)
+		}
+
+		for _, o := range page.Contents {
+			if err := fn(Object{Key: o.Key, Size: o.Size, ModTime: o.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// sign adds the AWS Signature Version 4 headers to a request. The host and
+// every header set on the request so far are signed.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	canonical, signedHeaders := canonicalRequest(req.Method, req.URL.Path, req.URL.Query(), headers, payloadHash)
+	scope, signature := s.signature(now, canonical)
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// maxPresignExpiry is the longest validity SigV4 allows a presigned URL.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// PresignGet returns a link that fetches an object without credentials,
+// signed at signedAt and valid for expires after that. The bucket itself
+// can then stay private.
+func (s *S3) PresignGet(key string, signedAt time.Time, expires time.Duration) (string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	signedAt = signedAt.UTC()
+	expires = min(expires, maxPresignExpiry)
+
+	scope := signedAt.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+	q := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.cfg.AccessKey + "/" + scope},
+		"X-Amz-Date":          {signedAt.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.Itoa(int(expires.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	canonical, _ := canonicalRequest(http.MethodGet, u.Path, q, map[string]string{"host": u.Host}, unsignedPayload)
+	_, signature := s.signature(signedAt, canonical)
+	q.Set("X-Amz-Signature", signature)
+
+	u.RawQuery = canonicalQuery(q)
+	return u.String(), nil
+}
+
+// canonicalRequest builds the SigV4 canonical request of a request with the
+// given lower-case headers, and the list of the headers it signs.
+func canonicalRequest(method, path string, query url.Values, headers map[string]string, payloadHash string) (canonical, signedHeaders string) {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	canonical = strings.Join([]string{
+		method,
+		uriEncode(path, false),
+		canonicalQuery(query),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	return canonical, signedHeaders
+}
+
+// signature signs a canonical request made at t and returns the credential
+// scope with the signature.
+func (s *S3) signature(t time.Time, canonical string) (scope, signature string) {
+	date := t.Format("20060102")
+	scope = date + "/" + s.cfg.Region + "/s3/aws4_request"
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		t.Format("20060102T150405Z"),
+		scope,
+		hexSHA256(canonical),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, as SigV4
+// requires. Slashes are kept unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestS3Integration runs the S3 backend against a real server, such as a
+// local MinIO:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//
+// It needs S3_TEST_ENDPOINT, S3_TEST_BUCKET (an existing, private bucket),
+// S3_TEST_ACCESS_KEY and S3_TEST_SECRET_KEY, and is skipped without them.
+func TestS3Integration(t *testing.T) {
+	cfg := S3Config{
+		Endpoint:  os.Getenv("S3_TEST_ENDPOINT"),
+		Region:    os.Getenv("S3_TEST_REGION"),
+		Bucket:    os.Getenv("S3_TEST_BUCKET"),
+		AccessKey: os.Getenv("S3_TEST_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_TEST_SECRET_KEY"),
+		PublicURL: "http://api.test/media",
+	}
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		t.Skip("S3_TEST_ENDPOINT, S3_TEST_BUCKET, S3_TEST_ACCESS_KEY and S3_TEST_SECRET_KEY are not set")
+	}
+	s, err := NewS3(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	prefix := "test-" + time.Now().Format("20060102150405.000000000") + "/"
+	key, copied := prefix+"a b.txt", prefix+"copy.txt"
+	t.Cleanup(func() {
+		_ = s.Delete(ctx, key)
+		_ = s.Delete(ctx, copied)
+	})
+
+	if err := s.Put(ctx, key, strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, s, key); got != "hello" {
+		t.Errorf("read %q", got)
+	}
+	if err := s.Put(ctx, key, strings.NewReader("hello again"), -1, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Copy(ctx, key, copied); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, s, copied); got != "hello again" {
+		t.Errorf("copy holds %q", got)
+	}
+	if err := s.Copy(ctx, prefix+"missing.txt", prefix+"other.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Copy of a missing object = %v, want ErrNotFound", err)
+	}
+
+	var listed []string
+	if err := s.List(ctx, prefix, func(o Object) error {
+		listed = append(listed, o.Key)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 {
+		t.Errorf("List(%q) = %v, want 2 objects", prefix, listed)
+	}
+
+	// The bucket is private: the object is only readable through a
+	// presigned link.
+	link, err := s.PresignGet(key, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "hello again" {
+		t.Errorf("presigned GET = %s %q", res.Status, body)
+	}
+	u, _ := s.objectURL(key)
+	if res, err := http.Get(u.String()); err == nil {
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			t.Error("the bucket serves objects without a signature; it must be private")
+		}
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing object = %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Object describes a stored object.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage keeps uploaded files as objects under keys such as
+// "storage/recipe_1_w1600.jpg". The database stores keys, never URLs; URL
+// turns a key into a link when a response is built.
+type Storage interface {
+	// Put writes size bytes from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open reads an object. A missing object yields ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Copy duplicates an object. A missing source yields ErrNotFound.
+	Copy(ctx context.Context, from, to string) error
+	// URL returns the link clients use to fetch an object.
+	URL(key string) string
+	// List calls fn for every object whose key starts with prefix and stops
+	// at the first error fn returns.
+	List(ctx context.Context, prefix string, fn func(Object) error) error
+}
+
+// Presigner is implemented by storage that can hand out expiring links to
+// its objects itself, such as a private S3 bucket.
+type Presigner interface {
+	// PresignGet returns a link to an object signed at signedAt and valid
+	// for expires after that.
+	PresignGet(key string, signedAt time.Time, expires time.Duration) (string, error)
+}
+
+// Default is the storage the services use, set up by FromEnv.
+var Default Storage
+
+// FromEnv sets Default from STORAGE_DRIVER, "local" (the default) or "s3".
+func FromEnv() (Storage, error) {
+	var s Storage
+	switch driver := strings.ToLower(os.Getenv("STORAGE_DRIVER")); driver {
+	case "", "local":
+		root := os.Getenv("STORAGE_LOCAL_ROOT")
+		if root == "" {
+			root = "public"
+		}
+		s = NewLocal(root, strings.TrimRight(os.Getenv("API_IMAGE_PATH"), "/")+MediaPath)
+	case "s3":
+		// Public objects are served by the media handler, which checks that
+		// they are public, unless S3_PUBLIC_URL overrides it.
+		publicURL := os.Getenv("S3_PUBLIC_URL")
+		if publicURL == "" {
+			publicURL = strings.TrimRight(os.Getenv("API_IMAGE_PATH"), "/") + MediaPath
+		}
+		s3, err := NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: publicURL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s = s3
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+	Default = s
+	return s, nil
+}
+
+// CleanKey normalises a key and rejects keys that would leave the storage
+// root, such as "../x" or "/etc/passwd".
+func CleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != key {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return cleaned, nil
+}
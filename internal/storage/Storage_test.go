@@ -0,0 +1,19 @@
+package storage
+
+import "testing"
+
+func TestCleanKey(t *testing.T) {
+	valid := []string{"content/ab_w320.jpg", "storage/recipe_1.png", "uploads/id/00000000000000000000"}
+	for _, key := range valid {
+		if got, err := CleanKey(key); err != nil || got != key {
+			t.Errorf("CleanKey(%q) = %q, %v; want it unchanged", key, got, err)
+		}
+	}
+
+	invalid := []string{"", "/etc/passwd", "../secret", "content/../../secret", "content//a.jpg", "content/./a.jpg", "content/", "."}
+	for _, key := range invalid {
+		if got, err := CleanKey(key); err == nil {
+			t.Errorf("CleanKey(%q) = %q, want an error", key, got)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ImagePlaceholder is what clients show while an image loads: a BlurHash
+// (https://blurha.sh) and the image's dominant color as "#rrggbb".
+type ImagePlaceholder struct {
+	BlurHash string
+	Color    string
+}
+
+const (
+	// placeholderSize is the longest side images are scaled down to before
+	// the placeholder is computed; a blur needs no more detail than that.
+	placeholderSize = 32
+	// blurHashComponents is the number of components along the longer side;
+	// the shorter side gets one less.
+	blurHashComponents = 4
+)
+
+// Placeholder computes the placeholder of an image. Transparent parts count
+// as white, as they do in the JPEG variants.
+func Placeholder(img image.Image) ImagePlaceholder {
+	small := placeholderImage(img)
+	xc, yc := blurHashComponents, blurHashComponents-1
+	if small.Bounds().Dy() > small.Bounds().Dx() {
+		xc, yc = yc, xc
+	}
+	return ImagePlaceholder{
+		BlurHash: encodeBlurHash(small, xc, yc),
+		Color:    dominantColor(small),
+	}
+}
+
+// DecodePlaceholder decodes an image file and computes its placeholder.
+func DecodePlaceholder(r io.Reader) (ImagePlaceholder, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ImagePlaceholder{}, err
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return ImagePlaceholder{}, err
+	}
+	return Placeholder(img), nil
+}
+
+func placeholderImage(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w >= h {
+		w, h = min(w, placeholderSize), max(1, (h*min(w, placeholderSize)+w/2)/w)
+	} else {
+		w, h = max(1, (w*min(h, placeholderSize)+h/2)/h), min(h, placeholderSize)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, xdraw.Over, nil)
+	return dst
+}
+
+// encodeBlurHash encodes an image as a BlurHash with xc by yc components,
+// each between 1 and 9.
+func encodeBlurHash(img *image.RGBA, xc, yc int) string {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	linear := make([][3]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := img.Pix[img.PixOffset(x, y):]
+			linear[y*w+x] = [3]float64{srgbToLinear(p[0]), srgbToLinear(p[1]), srgbToLinear(p[2])}
+		}
+	}
+
+	factors := make([][3]float64, 0, xc*yc)
+	for j := 0; j < yc; j++ {
+		for i := 0; i < xc; i++ {
+			norm := 2.0
+			if i == 0 && j == 0 {
+				norm = 1
+			}
+			var f [3]float64
+			for y := 0; y < h; y++ {
+				cy := math.Cos(math.Pi * float64(j) * float64(y) / float64(h))
+				for x := 0; x < w; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) * cy
+					px := linear[y*w+x]
+					f[0] += basis * px[0]
+					f[1] += basis * px[1]
+					f[2] += basis * px[2]
+				}
+			}
+			scale := norm / float64(w*h)
+			factors = append(factors, [3]float64{f[0] * scale, f[1] * scale, f[2] * scale})
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(encode83((xc-1)+(yc-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	maxValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = max(actualMax, math.Abs(f[0]), math.Abs(f[1]), math.Abs(f[2]))
+		}
+		quantised := max(0, min(82, int(math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantised+1) / 166
+		sb.WriteString(encode83(quantised, 1))
+	} else {
+		sb.WriteString(encode83(0, 1))
+	}
+
+	sb.WriteString(encode83(linearToSrgb(dc[0])<<16|linearToSrgb(dc[1])<<8|linearToSrgb(dc[2]), 4))
+	for _, f := range ac {
+		q := func(v float64) int {
+			return max(0, min(18, int(math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+		}
+		sb.WriteString(encode83(q(f[0])*19*19+q(f[1])*19+q(f[2]), 2))
+	}
+	return sb.String()
+}
+
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func encode83(value, length int) string {
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = base83[value%83]
+		value /= 83
+	}
+	return string(out)
+}
+
+func srgbToLinear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func linearToSrgb(v float64) int {
+	v = max(0, min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+// dominantColor returns the average of the most common colors of an image,
+// grouping colors that match in their top four bits per channel.
+func dominantColor(img *image.RGBA) string {
+	type bucket struct{ n, r, g, b int }
+	buckets := map[int]*bucket{}
+	best := -1
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			p := img.Pix[img.PixOffset(x, y):]
+			id := int(p[0]>>4)<<8 | int(p[1]>>4)<<4 | int(p[2]>>4)
+			bk := buckets[id]
+			if bk == nil {
+				bk = &bucket{}
+				buckets[id] = bk
+			}
+			bk.n++
+			bk.r += int(p[0])
+			bk.g += int(p[1])
+			bk.b += int(p[2])
+			if best < 0 || bk.n > buckets[best].n || (bk.n == buckets[best].n && id < best) {
+				best = id
+			}
+		}
+	}
+	bk := buckets[best]
+	return fmt.Sprintf("#%02x%02x%02x", bk.r/bk.n, bk.g/bk.n, bk.b/bk.n)
+}
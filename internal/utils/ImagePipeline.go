@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ImageWidths are the widths every upload is resized to. Images narrower
+// than the largest width also keep their own width, and are never upscaled.
+var ImageWidths = []int{320, 640, 1024, 1600}
+
+// ImageFormats are the formats every size is written in, by file extension.
+var ImageFormats = []string{"webp", "jpg"}
+
+const (
+	maxImagePixels = 40_000_000
+	jpegQuality    = 82
+)
+
+var ErrInvalidImage = errors.New("file is not a valid jpg, png or webp image")
+
+// ImageVariant is one encoded size of a processed image.
+type ImageVariant struct {
+	Width  int
+	Height int
+	Format string
+	Data   []byte
+}
+
+// ProcessImage decodes an uploaded image by its content, turns it upright
+// using its EXIF orientation and encodes it at every width in ImageWidths,
+// as WebP and JPEG. Re-encoding drops all metadata, EXIF and GPS included.
+// It also returns the placeholder shown while the image loads.
+func ProcessImage(r io.Reader) ([]ImageVariant, ImagePlaceholder, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, ImagePlaceholder{}, err
+	}
+
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil, ImagePlaceholder{}, err
+	}
+
+	var variants []ImageVariant
+	for _, width := range variantWidths(img.Bounds().Dx()) {
+		scaled := resize(img, width)
+		for _, ext := range ImageFormats {
+			var buf bytes.Buffer
+			if err := encodeImage(&buf, scaled, ext); err != nil {
+				return nil, ImagePlaceholder{}, err
+			}
+			variants = append(variants, ImageVariant{
+				Width:  width,
+				Height: scaled.Bounds().Dy(),
+				Format: ext,
+				Data:   buf.Bytes(),
+			})
+		}
+	}
+	return variants, Placeholder(img), nil
+}
+
+// decodeImage decodes a jpg, png or webp file by its content and turns it
+// upright using its EXIF orientation.
+func decodeImage(data []byte) (image.Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width < 1 || cfg.Height < 1 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, errors.New("image dimensions are too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, nil
+}
+
+// variantWidths lists the widths an image of the given width is stored at.
+func variantWidths(width int) []int {
+	largest := min(width, ImageWidths[len(ImageWidths)-1])
+	var out []int
+	for _, w := range ImageWidths {
+		if w < largest {
+			out = append(out, w)
+		}
+	}
+	return append(out, largest)
+}
+
+func resize(img image.Image, width int) *image.RGBA {
+	b := img.Bounds()
+	height := max(1, (b.Dy()*width+b.Dx()/2)/b.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
+
+func encodeImage(w io.Writer, img *image.RGBA, ext string) error {
+	switch ext {
+	case "webp":
+		return nativewebp.Encode(w, img, nil)
+	case "jpg":
+		// JPEG has no alpha channel, so transparent parts become white.
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: jpegQuality})
+	default:
+		return fmt.Errorf("unsupported image format %q", ext)
+	}
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG file. It returns
+// 1, upright, when the file has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient turns an image upright for an EXIF orientation between 1 and 8.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// VariantName is the file name of one size and format of a stored image.
+// The stored image itself is named after its largest JPEG, so the other
+// files can be found from its name alone.
+func VariantName(stem string, width int, ext string) string {
+	return fmt.Sprintf("%s_w%d.%s", stem, width, ext)
+}
+
+// parseVariantName splits a stored image name such as "recipe_1_w1600.jpg"
+// into its stem and largest width. Files stored before the image pipeline
+// have no width and report ok=false.
+func parseVariantName(name string) (stem string, width int, ok bool) {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	i := strings.LastIndex(base, "_w")
+	if i < 0 {
+		return "", 0, false
+	}
+	width, err := strconv.Atoi(base[i+2:])
+	if err != nil || width < 1 {
+		return "", 0, false
+	}
+	return base[:i], width, true
+}
+
+// ImageStem returns the key of a stored image, or of one of its variants,
+// without its "_w<width>.<ext>" suffix. Keys stored before the image
+// pipeline report ok=false.
+func ImageStem(key string) (stem string, ok bool) {
+	dir, name := path.Split(key)
+	stem, _, ok = parseVariantName(name)
+	return dir + stem, ok
+}
+
+// ImageFiles lists every object key that belongs to a stored image, the key
+// itself included.
+func ImageFiles(key string) []string {
+	dir, name := path.Split(key)
+	stem, width, ok := parseVariantName(name)
+	if !ok {
+		return []string{key}
+	}
+	var out []string
+	for _, w := range variantWidths(width) {
+		for _, ext := range ImageFormats {
+			out = append(out, dir+VariantName(stem, w, ext))
+		}
+	}
+	return out
+}
+
+// ImageSrcsets builds a srcset for every format of a stored image from its
+// key, such as {"webp": ".../a_w320.webp 320w, .../a_w640.webp 640w"}, using
+// url to link each variant. Images stored before the image pipeline have no
+// variants and yield nil.
+func ImageSrcsets(key string, url func(key string) string) map[string]string {
+	dir, name := path.Split(key)
+	stem, width, ok := parseVariantName(name)
+	if key == "" || !ok {
+		return nil
+	}
+
+	widths := variantWidths(width)
+	out := make(map[string]string, len(ImageFormats))
+	for _, ext := range ImageFormats {
+		parts := make([]string, 0, len(widths))
+		for _, w := range widths {
+			parts = append(parts, fmt.Sprintf("%s %dw", url(dir+VariantName(stem, w, ext)), w))
+		}
+		out[ext] = strings.Join(parts, ", ")
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// exifJPEG builds the start of a JPEG whose EXIF block, in the given byte
+// order, holds one orientation tag.
+func exifJPEG(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(data[4:], uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, 0xFF, 0xDA, 0, 2)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		for o := 1; o <= 8; o++ {
+			data := exifJPEG(order, uint16(o))
+			if got := jpegOrientation(data); got != o {
+				t.Errorf("%v: jpegOrientation = %d, want %d", order, got, o)
+			}
+			if got := exifOrientation(data[12 : len(data)-4]); got != o {
+				t.Errorf("%v: exifOrientation = %d, want %d", order, got, o)
+			}
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"out of range": exifJPEG(binary.BigEndian, 9),
+		"no exif":      {0xFF, 0xD8, 0xFF, 0xDA, 0, 2},
+		"not a jpeg":   []byte("\x89PNG\r\n\x1a\n"),
+		"truncated":    exifJPEG(binary.BigEndian, 6)[:20],
+	} {
+		if got := jpegOrientation(data); got != 1 {
+			t.Errorf("%s: jpegOrientation = %d, want 1", name, got)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// The upright image is
+	//   1 2 3
+	//   4 5 6
+	// and stored holds how a camera writes it for each orientation.
+	upright := [][]uint8{{1, 2, 3}, {4, 5, 6}}
+	stored := map[int][][]uint8{
+		1: {{1, 2, 3}, {4, 5, 6}},
+		2: {{3, 2, 1}, {6, 5, 4}},
+		3: {{6, 5, 4}, {3, 2, 1}},
+		4: {{4, 5, 6}, {1, 2, 3}},
+		5: {{1, 4}, {2, 5}, {3, 6}},
+		6: {{3, 6}, {2, 5}, {1, 4}},
+		7: {{6, 3}, {5, 2}, {4, 1}},
+		8: {{4, 1}, {5, 2}, {6, 3}},
+	}
+
+	for o := 1; o <= 8; o++ {
+		rows := stored[o]
+		img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+		for y, row := range rows {
+			for x, v := range row {
+				img.SetGray(x, y, color.Gray{Y: v})
+			}
+		}
+
+		got := orient(img, o)
+		b := got.Bounds()
+		pixels := make([][]uint8, b.Dy())
+		for y := range pixels {
+			pixels[y] = make([]uint8, b.Dx())
+			for x := range pixels[y] {
+				pixels[y][x] = color.GrayModel.Convert(got.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+			}
+		}
+		if !reflect.DeepEqual(pixels, upright) {
+			t.Errorf("orient(%d) = %v, want %v", o, pixels, upright)
+		}
+	}
+}
+
+func TestVariantWidths(t *testing.T) {
+	tests := []struct {
+		width int
+		want  []int
+	}{
+		{100, []int{100}},
+		{320, []int{320}},
+		{800, []int{320, 640, 800}},
+		{1600, []int{320, 640, 1024, 1600}},
+		{4000, []int{320, 640, 1024, 1600}},
+	}
+	for _, tt := range tests {
+		if got := variantWidths(tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("variantWidths(%d) = %v, want %v", tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestImageFiles(t *testing.T) {
+	key := "recipes/" + VariantName("recipe_1", 800, "jpg")
+	want := []string{
+		"recipes/recipe_1_w320.webp", "recipes/recipe_1_w320.jpg",
+		"recipes/recipe_1_w640.webp", "recipes/recipe_1_w640.jpg",
+		"recipes/recipe_1_w800.webp", "recipes/recipe_1_w800.jpg",
+	}
+	files := ImageFiles(key)
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("ImageFiles(%q) = %v, want %v", key, files, want)
+	}
+	for _, f := range files {
+		if stem, ok := ImageStem(f); !ok || stem != "recipes/recipe_1" {
+			t.Errorf("ImageStem(%q) = %q, %v; want recipes/recipe_1, true", f, stem, ok)
+		}
+	}
+
+	// Files stored before the image pipeline have no variants.
+	legacy := "recipes/1700000000_photo.jpg"
+	if got := ImageFiles(legacy); !reflect.DeepEqual(got, []string{legacy}) {
+		t.Errorf("ImageFiles(%q) = %v, want only the key", legacy, got)
+	}
+	if _, ok := ImageStem(legacy); ok {
+		t.Errorf("ImageStem(%q) reported a variant", legacy)
+	}
+}
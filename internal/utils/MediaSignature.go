@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultMediaURLTTL = time.Hour
+
+// MediaURLTTL is how long a signed media URL stays valid, from
+// MEDIA_URL_TTL (a duration such as "30m") or one hour.
+func MediaURLTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("MEDIA_URL_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return defaultMediaURLTTL
+}
+
+func mediaSecret() []byte {
+	if secret := os.Getenv("MEDIA_URL_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return []byte(os.Getenv("ACCESS_TOKEN_SECRET"))
+}
+
+// MediaExpiry picks the expiry of a media URL signed at now. It is rounded
+// to the TTL so a file keeps the same URL, and stays cacheable, for a while;
+// every URL is valid for at least one TTL.
+func MediaExpiry(now time.Time) time.Time {
+	ttl := MediaURLTTL()
+	return now.Truncate(ttl).Add(2 * ttl)
+}
+
+// SignMedia returns the signature that allows key to be fetched until
+// expires.
+func SignMedia(key string, expires time.Time) string {
+	mac := hmac.New(sha256.New, mediaSecret())
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires.Unix(), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyMedia reports whether signature allows key to be fetched at now.
+func VerifyMedia(key string, expires int64, signature string, now time.Time) bool {
+	if now.Unix() > expires {
+		return false
+	}
+	want := SignMedia(key, time.Unix(expires, 0))
+	return hmac.Equal([]byte(want), []byte(signature))
+}
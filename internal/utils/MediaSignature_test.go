@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMediaExpiry(t *testing.T) {
+	t.Setenv("MEDIA_URL_TTL", "1h")
+	base := time.Unix(1_700_000_000, 0).Truncate(time.Hour)
+
+	for _, offset := range []time.Duration{0, time.Minute, 59 * time.Minute} {
+		now := base.Add(offset)
+		expires := MediaExpiry(now)
+		if want := base.Add(2 * time.Hour); !expires.Equal(want) {
+			t.Errorf("MediaExpiry(%v) = %v, want %v", now, expires, want)
+		}
+		if expires.Sub(now) < time.Hour {
+			t.Errorf("MediaExpiry(%v) is valid for less than one TTL", now)
+		}
+	}
+}
+
+func TestVerifyMedia(t *testing.T) {
+	t.Setenv("MEDIA_URL_SECRET", "media-secret")
+	now := time.Unix(1_700_000_000, 0)
+	key := "recipes/recipe_1_w640.webp"
+	expires := MediaExpiry(now)
+	sig := SignMedia(key, expires)
+
+	if !VerifyMedia(key, expires.Unix(), sig, now) {
+		t.Fatal("valid link rejected")
+	}
+	if !VerifyMedia(key, expires.Unix(), sig, expires) {
+		t.Error("link rejected at its expiry")
+	}
+	if VerifyMedia(key, expires.Unix(), sig, expires.Add(time.Second)) {
+		t.Error("expired link accepted")
+	}
+	if VerifyMedia("recipes/recipe_2_w640.webp", expires.Unix(), sig, now) {
+		t.Error("link accepted for another key")
+	}
+	if VerifyMedia(key, expires.Add(time.Hour).Unix(), sig, now) {
+		t.Error("link accepted with a later expiry")
+	}
+
+	tampered := []byte(sig)
+	tampered[0] ^= 1
+	for _, bad := range []string{"", string(tampered), sig[:len(sig)-1], sig + "A"} {
+		if VerifyMedia(key, expires.Unix(), bad, now) {
+			t.Errorf("VerifyMedia accepted signature %q", bad)
+		}
+	}
+
+	t.Setenv("MEDIA_URL_SECRET", "another-secret")
+	if VerifyMedia(key, expires.Unix(), sig, now) {
+		t.Error("link accepted under another secret")
+	}
+}
@@ -0,0 +1,18 @@
+package utils
+
+import "bytes"
+
+// SniffVideo recognises the video containers uploads may use from the first
+// bytes of a file. It returns empty strings for anything else.
+func SniffVideo(head []byte) (contentType, ext string) {
+	switch {
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		if string(head[8:12]) == "qt  " {
+			return "video/quicktime", "mov"
+		}
+		return "video/mp4", "mp4"
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "video/webm", "webm"
+	}
+	return "", ""
+}
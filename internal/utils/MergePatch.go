@@ -0,0 +1,24 @@
+package utils
+
+// MergePatch applies an RFC 7396 JSON merge patch to a decoded JSON document.
+// Objects are merged key by key, a null value removes the key, and any other
+// value (including arrays) replaces the target outright.
+func MergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = MergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// unitDef describes a known measuring unit. Factor is the size of one unit
+// expressed in the base unit of its kind (ml for volume, g for mass).
+type unitDef struct {
+	Name   string
+	Kind   string
+	Factor float64
+	Ladder string
+}
+
+const (
+	KindVolume = "volume"
+	KindMass   = "mass"
+	KindCount  = "count"
+)
+
+var units = map[string]unitDef{
+	"tsp":  {Name: "tsp", Kind: KindVolume, Factor: 4.92892, Ladder: "us_volume"},
+	"tbsp": {Name: "tbsp", Kind: KindVolume, Factor: 14.7868, Ladder: "us_volume"},
+	"cup":  {Name: "cup", Kind: KindVolume, Factor: 236.588, Ladder: "us_volume"},
+	"ml":   {Name: "ml", Kind: KindVolume, Factor: 1, Ladder: "metric_volume"},
+	"l":    {Name: "l", Kind: KindVolume, Factor: 1000, Ladder: "metric_volume"},
+	"g":    {Name: "g", Kind: KindMass, Factor: 1, Ladder: "metric_mass"},
+	"kg":   {Name: "kg", Kind: KindMass, Factor: 1000, Ladder: "metric_mass"},
+	"oz":   {Name: "oz", Kind: KindMass, Factor: 28.3495, Ladder: "us_mass"},
+	"lb":   {Name: "lb", Kind: KindMass, Factor: 453.592, Ladder: "us_mass"},
+}
+
+// ladders lists units from smallest to largest; scaled amounts are promoted
+// or demoted along the ladder of their unit.
+var ladders = map[string][]string{
+	"us_volume":     {"tsp", "tbsp", "cup"},
+	"metric_volume": {"ml", "l"},
+	"metric_mass":   {"g", "kg"},
+	"us_mass":       {"oz", "lb"},
+}
+
+var unitAliases = map[string]string{
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp", "sdt": "tsp", "sendok teh": "tsp",
+	"tbsp": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp", "sdm": "tbsp", "sendok makan": "tbsp",
+	"cup": "cup", "cups": "cup", "c": "cup", "gelas": "cup",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml", "mililiter": "ml", "cc": "ml",
+	"l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l", "ltr": "l",
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "grm": "g",
+	"kg": "kg", "kilogram": "kg", "kilograms": "kg", "kilo": "kg",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+}
+
+var unicodeFractions = map[rune]float64{
+	'¼': 0.25, '½': 0.5, '¾': 0.75, '⅓': 1.0 / 3, '⅔': 2.0 / 3, '⅛': 0.125, '⅜': 0.375, '⅝': 0.625, '⅞': 0.875,
+}
+
+// NormalizeUnit maps a free-text unit ("sdm", "Tablespoons") to its canonical
+// name. Units that are not recognised are returned lowercased and trimmed.
+func NormalizeUnit(unit string) string {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	u = strings.TrimSuffix(u, ".")
+	if canonical, ok := unitAliases[u]; ok {
+		return canonical
+	}
+	return u
+}
+
+// UnitKind returns the kind of a canonical unit, or KindCount for units that
+// cannot be converted (pieces, cloves, pinches, ...).
+func UnitKind(unit string) string {
+	if def, ok := units[unit]; ok {
+		return def.Kind
+	}
+	return KindCount
+}
+
+// ParseAmount splits a free-text amount such as "1 1/2 sdm", "200gr" or "½ cup"
+// into a quantity and a canonical unit. The unit is the word after the
+// number, or two words for units such as "sendok makan"; anything after it
+// is returned as the name ("2 sdm gula pasir" names "gula pasir"). ok is
+// false when the text does not start with a number (e.g. "secukupnya").
+func ParseAmount(amount string) (qty float64, unit, name string, ok bool) {
+	s := strings.TrimSpace(amount)
+	if s == "" {
+		return 0, "", "", false
+	}
+
+	qty, rest, ok := parseNumber(s)
+	if !ok {
+		return 0, "", "", false
+	}
+
+	// Mixed number: "1 1/2", "1 ½".
+	if next, tail, more := parseNumber(strings.TrimSpace(rest)); more && next < 1 && strings.HasPrefix(rest, " ") {
+		qty += next
+		rest = tail
+	}
+
+	unit, name = splitUnit(rest)
+	return qty, unit, name, true
+}
+
+// splitUnit takes the unit off the text that follows a number and returns
+// it in canonical form with the rest of the text.
+func splitUnit(rest string) (unit, name string) {
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	if len(fields) > 1 {
+		if _, ok := unitAliases[strings.ToLower(fields[0]+" "+fields[1])]; ok {
+			return NormalizeUnit(fields[0] + " " + fields[1]), strings.Join(fields[2:], " ")
+		}
+	}
+	return NormalizeUnit(fields[0]), strings.Join(fields[1:], " ")
+}
+
+func parseNumber(s string) (float64, string, bool) {
+	if s == "" {
+		return 0, "", false
+	}
+
+	runes := []rune(s)
+	if v, found := unicodeFractions[runes[0]]; found {
+		return v, string(runes[1:]), true
+	}
+
+	end := 0
+	for end < len(s) && (unicode.IsDigit(rune(s[end])) || s[end] == '.' || s[end] == ',' || s[end] == '/') {
+		end++
+	}
+	if end == 0 {
+		return 0, s, false
+	}
+
+	token := strings.ReplaceAll(s[:end], ",", ".")
+	rest := s[end:]
+
+	var value float64
+	if num, den, isFraction := strings.Cut(token, "/"); isFraction {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, s, false
+		}
+		value = n / d
+	} else {
+		v, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return 0, s, false
+		}
+		value = v
+	}
+
+	// Unicode fraction right after the whole part: "1½".
+	if r := []rune(rest); len(r) > 0 {
+		if v, found := unicodeFractions[r[0]]; found {
+			value += v
+			rest = string(r[1:])
+		}
+	}
+
+	return value, rest, true
+}
+
+// ScaleQuantity multiplies qty by factor and re-expresses the result in the
+// most readable unit of the same ladder (e.g. 16 tbsp becomes 1 cup).
+func ScaleQuantity(qty float64, unit string, factor float64) (float64, string) {
+	return PromoteUnit(qty*factor, unit)
+}
+
+// unitTolerance is the relative error allowed when comparing converted
+// amounts: the unit factors are rounded, so 3 tsp comes to 0.999997 tbsp.
+const unitTolerance = 1e-4
+
+// PromoteUnit moves a quantity up or down its unit ladder so that it is
+// expressed in the largest unit where the amount is still at least one
+// kitchen-friendly fraction.
+func PromoteUnit(qty float64, unit string) (float64, string) {
+	def, ok := units[unit]
+	if !ok || qty <= 0 {
+		return qty, unit
+	}
+
+	base := qty * def.Factor
+	steps := ladders[def.Ladder]
+	best := steps[0]
+	for _, candidate := range steps {
+		v := base / units[candidate].Factor
+		if v >= minimumFor(candidate)*(1-unitTolerance) {
+			best = candidate
+		}
+	}
+	v := base / units[best].Factor
+	if whole := math.Round(v); whole > 0 && math.Abs(v-whole) <= whole*unitTolerance {
+		v = whole
+	}
+	return v, best
+}
+
+func minimumFor(unit string) float64 {
+	switch unit {
+	case "cup":
+		return 0.25
+	default:
+		return 1
+	}
+}
+
+var kitchenFractions = []struct {
+	value float64
+	text  string
+}{
+	{0, ""}, {0.125, "1/8"}, {0.25, "1/4"}, {1.0 / 3, "1/3"}, {0.5, "1/2"},
+	{2.0 / 3, "2/3"}, {0.75, "3/4"}, {1, ""},
+}
+
+// RoundQuantity rounds a quantity to a precision that makes sense for its
+// unit: common kitchen fractions for spoons, cups and counts, whole numbers
+// for grams and millilitres, and two decimals for kilograms and litres.
+func RoundQuantity(qty float64, unit string) float64 {
+	switch unit {
+	case "g", "ml":
+		if qty >= 100 {
+			return math.Round(qty/5) * 5
+		}
+		return math.Max(1, math.Round(qty))
+	case "kg", "l":
+		return math.Round(qty*100) / 100
+	}
+
+	whole := math.Floor(qty)
+	frac := qty - whole
+	closest := kitchenFractions[0]
+	for _, f := range kitchenFractions {
+		if math.Abs(frac-f.value) < math.Abs(frac-closest.value) {
+			closest = f
+		}
+	}
+	rounded := whole + closest.value
+	if rounded == 0 && qty > 0 {
+		return kitchenFractions[1].value
+	}
+	return rounded
+}
+
+// FormatQuantity renders a quantity with kitchen fractions, e.g. 1.5 → "1 1/2".
+func FormatQuantity(qty float64, unit string) string {
+	qty = RoundQuantity(qty, unit)
+
+	switch unit {
+	case "g", "ml", "kg", "l":
+		return strconv.FormatFloat(qty, 'f', -1, 64)
+	}
+
+	whole := math.Floor(qty + 1e-9)
+	fracText := ""
+	for _, f := range kitchenFractions {
+		if f.text != "" && math.Abs(qty-whole-f.value) < 1e-6 {
+			fracText = f.text
+		}
+	}
+
+	switch {
+	case whole == 0 && fracText != "":
+		return fracText
+	case fracText == "":
+		return strconv.FormatFloat(whole, 'f', 0, 64)
+	default:
+		return fmt.Sprintf("%.0f %s", whole, fracText)
+	}
+}
+
+// FormatAmount renders a quantity and unit back into a human-readable amount.
+func FormatAmount(qty float64, unit string) string {
+	if unit == "" {
+		return FormatQuantity(qty, unit)
+	}
+	return FormatQuantity(qty, unit) + " " + unit
+}
+
+// RebuildAmount renders qty and unit as an amount and keeps the text that
+// followed the unit in the original amount, so "3 bawang merah" doubled
+// reads "6 bawang merah" rather than "6 bawang".
+func RebuildAmount(amount string, qty float64, unit string) string {
+	out := FormatAmount(qty, unit)
+	if _, _, name, ok := ParseAmount(amount); ok && name != "" {
+		out += " " + name
+	}
+	return out
+}
+
+// ScaleAmount scales an ingredient quantity by factor, rounds it for its
+// unit and returns it with the rebuilt amount text.
+func ScaleAmount(amount string, qty float64, unit string, factor float64) (float64, string, string) {
+	qty, unit = ScaleQuantity(qty, unit, factor)
+	qty = RoundQuantity(qty, unit)
+	return qty, unit, RebuildAmount(amount, qty, unit)
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		qty  float64
+		unit string
+		name string
+		ok   bool
+	}{
+		{"2 sdm", 2, "tbsp", "", true},
+		{"2 sdm gula pasir", 2, "tbsp", "gula pasir", true},
+		{"200gr tepung", 200, "g", "tepung", true},
+		{"1 1/2 cup", 1.5, "cup", "", true},
+		{"1½ cups milk", 1.5, "cup", "milk", true},
+		{"½ sendok teh garam", 0.5, "tsp", "garam", true},
+		{"0,5 kg", 0.5, "kg", "", true},
+		{"3 butir telur", 3, "butir", "telur", true},
+		{"2", 2, "", "", true},
+		{"secukupnya", 0, "", "", false},
+		{"", 0, "", "", false},
+	}
+	for _, tt := range tests {
+		qty, unit, name, ok := ParseAmount(tt.in)
+		if ok != tt.ok || math.Abs(qty-tt.qty) > 1e-9 || unit != tt.unit || name != tt.name {
+			t.Errorf("ParseAmount(%q) = %v, %q, %q, %v; want %v, %q, %q, %v",
+				tt.in, qty, unit, name, ok, tt.qty, tt.unit, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestPromoteUnit(t *testing.T) {
+	tests := []struct {
+		qty      float64
+		unit     string
+		wantQty  float64
+		wantUnit string
+	}{
+		{3, "tsp", 1, "tbsp"},
+		{2, "tsp", 2, "tsp"},
+		{16, "tbsp", 1, "cup"},
+		{4, "tbsp", 0.25, "cup"},
+		{1500, "g", 1.5, "kg"},
+		{0.5, "kg", 500, "g"},
+		{2000, "ml", 2, "l"},
+		{3, "butir", 3, "butir"},
+		{0, "g", 0, "g"},
+	}
+	for _, tt := range tests {
+		qty, unit := PromoteUnit(tt.qty, tt.unit)
+		if unit != tt.wantUnit || math.Abs(qty-tt.wantQty) > 1e-3 {
+			t.Errorf("PromoteUnit(%v, %q) = %v %s; want %v %s", tt.qty, tt.unit, qty, unit, tt.wantQty, tt.wantUnit)
+		}
+	}
+}
+
+func TestScaleQuantity(t *testing.T) {
+	tests := []struct {
+		qty      float64
+		unit     string
+		factor   float64
+		wantQty  float64
+		wantUnit string
+	}{
+		{1, "tsp", 3, 1, "tbsp"},
+		{1, "cup", 0.5, 0.5, "cup"},
+		{2, "tbsp", 8, 1, "cup"},
+		{500, "g", 3, 1.5, "kg"},
+		{2, "butir", 1.5, 3, "butir"},
+	}
+	for _, tt := range tests {
+		qty, unit := ScaleQuantity(tt.qty, tt.unit, tt.factor)
+		if unit != tt.wantUnit || math.Abs(qty-tt.wantQty) > 1e-3 {
+			t.Errorf("ScaleQuantity(%v, %q, %v) = %v %s; want %v %s",
+				tt.qty, tt.unit, tt.factor, qty, unit, tt.wantQty, tt.wantUnit)
+		}
+	}
+}
+
+func TestRoundQuantity(t *testing.T) {
+	tests := []struct {
+		qty  float64
+		unit string
+		want float64
+	}{
+		{1.3, "cup", 1 + 1.0/3},
+		{0.9999, "tbsp", 1},
+		{0.05, "tsp", 0.125},
+		{2.6, "butir", 2 + 2.0/3},
+		{123, "g", 125},
+		{42.4, "ml", 42},
+		{0.2, "g", 1},
+		{1.234, "kg", 1.23},
+	}
+	for _, tt := range tests {
+		if got := RoundQuantity(tt.qty, tt.unit); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("RoundQuantity(%v, %q) = %v; want %v", tt.qty, tt.unit, got, tt.want)
+		}
+	}
+}
+
+// TestScaleAmount covers the amounts ScaleRecipe and LocalizeRecipe render.
+func TestScaleAmount(t *testing.T) {
+	tests := []struct {
+		amount string
+		factor float64
+		want   string
+	}{
+		{"2 sdm gula pasir", 2, "1/4 cup gula pasir"},
+		{"3 bawang merah", 2, "6 bawang merah"},
+		{"1 tsp garam halus", 3, "1 tbsp garam halus"},
+		{"200 g", 1.5, "300 g"},
+	}
+	for _, tt := range tests {
+		qty, unit, _, _ := ParseAmount(tt.amount)
+		if _, _, got := ScaleAmount(tt.amount, qty, unit, tt.factor); got != tt.want {
+			t.Errorf("ScaleAmount(%q, x%v) = %q, want %q", tt.amount, tt.factor, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizeAmount(t *testing.T) {
+	tests := []struct {
+		amount, ingredient, system, want string
+	}{
+		{"1 cup susu cair", "susu", UnitSystemMetric, "245 g susu cair"},
+		{"500 g daging sapi", "daging sapi", UnitSystemUS, "1 1/8 lb daging sapi"},
+		{"2 butir telur", "telur", UnitSystemUS, "2 butir telur"},
+	}
+	for _, tt := range tests {
+		qty, unit, _, _ := ParseAmount(tt.amount)
+		if _, _, got := LocalizeAmount(tt.amount, qty, unit, tt.ingredient, tt.system); got != tt.want {
+			t.Errorf("LocalizeAmount(%q, %s) = %q, want %q", tt.amount, tt.system, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	slugSpaces  = regexp.MustCompile(`\s+`)
+	slugInvalid = regexp.MustCompile(`[^\p{L}\p{N}-]+`)
+	slugDashes  = regexp.MustCompile(`-{2,}`)
+)
+
+// Slugify turns a free-text name into a lowercase, dash-separated key, so
+// "Food", "food " and "FOOD" all become "food".
+func Slugify(name string) string {
+	slug := strings.ToLower(strings.TrimSpace(name))
+	slug = slugSpaces.ReplaceAllString(slug, "-")
+	slug = slugInvalid.ReplaceAllString(slug, "")
+	slug = slugDashes.ReplaceAllString(slug, "-")
+	return strings.Trim(slug, "-")
+}
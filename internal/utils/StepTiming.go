@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var durationPattern = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?|½|setengah|half an?)(?:\s*(?:-|–|sampai|hingga|to)\s*\d+(?:[.,]\d+)?)?\s*(jam|hours?|hrs?|menit|minutes?|mins?|detik|seconds?|secs?)\b`)
+
+var durationJoiner = regexp.MustCompile(`(?i)^\s*(?:,|dan|and)?\s*$`)
+
+func durationUnitSeconds(unit string) float64 {
+	unit = strings.ToLower(unit)
+	switch {
+	case unit == "jam" || strings.HasPrefix(unit, "h"):
+		return 3600
+	case unit == "menit" || strings.HasPrefix(unit, "min"):
+		return 60
+	default:
+		return 1
+	}
+}
+
+func durationValue(s string) (float64, bool) {
+	switch strings.ToLower(s) {
+	case "½", "setengah", "half a", "half an":
+		return 0.5, true
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	return v, err == nil
+}
+
+// ExtractDuration reads the first duration mentioned in a step, such as
+// "masak 15 menit", "1 jam 30 menit" or "simmer for 10-15 minutes", and
+// returns it in seconds. For a range the lower bound is used so the cook
+// checks early.
+func ExtractDuration(text string) (int, bool) {
+	matches := durationPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+
+	total := 0.0
+	end := -1
+	for _, m := range matches {
+		// Only add the parts of one compound duration: "1 jam 30 menit".
+		if end >= 0 && !durationJoiner.MatchString(text[end:m[0]]) {
+			break
+		}
+		value, ok := durationValue(text[m[2]:m[3]])
+		if !ok {
+			break
+		}
+		total += value * durationUnitSeconds(text[m[4]:m[5]])
+		end = m[1]
+	}
+
+	seconds := int(math.Round(total))
+	if seconds <= 0 {
+		return 0, false
+	}
+	return seconds, true
+}
+
+// ExtractTemperature reads the first temperature mentioned in a step, such
+// as "180°C" or "350 derajat F". A bare "derajat" or "°" is read as Celsius.
+func ExtractTemperature(text string) (value float64, unit string, ok bool) {
+	parts := temperaturePattern.FindStringSubmatch(text)
+	if parts == nil {
+		return 0, "", false
+	}
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	unit = "C"
+	if strings.HasPrefix(strings.ToLower(parts[2]+parts[3]), "f") {
+		unit = "F"
+	}
+	return value, unit, true
+}
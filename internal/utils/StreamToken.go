@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamTokenTTL is how long a cooking session stream token can be used to
+// connect. A stream that is already open outlives it.
+const StreamTokenTTL = time.Minute
+
+// streamSecret keeps stream tokens apart from access tokens and media
+// signatures made with the same secret.
+func streamSecret() []byte {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ACCESS_TOKEN_SECRET")))
+	mac.Write([]byte("cooking-session-stream"))
+	return mac.Sum(nil)
+}
+
+func signStream(userID, sessionID string, expires int64) string {
+	mac := hmac.New(sha256.New, streamSecret())
+	mac.Write([]byte(userID + "\n" + sessionID + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignStreamToken returns a token that lets userID open the event stream of
+// one cooking session until expires. It is passed in the query string by
+// clients, such as EventSource, that cannot send an Authorization header.
+func SignStreamToken(userID, sessionID string, expires time.Time) string {
+	return userID + "." + strconv.FormatInt(expires.Unix(), 10) + "." + signStream(userID, sessionID, expires.Unix())
+}
+
+// VerifyStreamToken returns the user a stream token for sessionID was
+// issued to, and false when the token is invalid or has expired at now.
+func VerifyStreamToken(token, sessionID string, now time.Time) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return "", false
+	}
+	want := signStream(parts[0], sessionID, expires)
+	if !hmac.Equal([]byte(want), []byte(parts[2])) {
+		return "", false
+	}
+	return parts[0], true
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamToken(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	token := SignStreamToken("user-1", "session-1", now.Add(StreamTokenTTL))
+
+	if user, ok := VerifyStreamToken(token, "session-1", now); !ok || user != "user-1" {
+		t.Errorf("VerifyStreamToken = %q, %v; want user-1, true", user, ok)
+	}
+	if _, ok := VerifyStreamToken(token, "session-2", now); ok {
+		t.Error("token accepted for another session")
+	}
+	if _, ok := VerifyStreamToken(token, "session-1", now.Add(StreamTokenTTL+time.Second)); ok {
+		t.Error("expired token accepted")
+	}
+	forged := "user-2" + token[len("user-1"):]
+	if _, ok := VerifyStreamToken(forged, "session-1", now); ok {
+		t.Error("token accepted for another user")
+	}
+	for _, bad := range []string{"", "a.b", "user-1.x.sig", token + "x"} {
+		if _, ok := VerifyStreamToken(bad, "session-1", now); ok {
+			t.Errorf("VerifyStreamToken(%q) accepted", bad)
+		}
+	}
+}
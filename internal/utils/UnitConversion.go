@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	UnitSystemMetric = "metric"
+	UnitSystemUS     = "us"
+)
+
+// unitSystems tells which measurement system a unit belongs to. Spoons are
+// used everywhere, so they are left out and never converted.
+var unitSystems = map[string]string{
+	"cup": UnitSystemUS,
+	"oz":  UnitSystemUS,
+	"lb":  UnitSystemUS,
+	"ml":  UnitSystemMetric,
+	"l":   UnitSystemMetric,
+	"g":   UnitSystemMetric,
+	"kg":  UnitSystemMetric,
+}
+
+// densities holds grams per millilitre for common ingredients, keyed by
+// keywords in English and Indonesian, used for volume↔mass conversion.
+var densities = map[string]float64{
+	"flour":          0.53,
+	"tepung terigu":  0.53,
+	"tepung":         0.53,
+	"powdered sugar": 0.51,
+	"gula halus":     0.51,
+	"brown sugar":    0.93,
+	"gula merah":     0.93,
+	"gula palem":     0.93,
+	"sugar":          0.85,
+	"gula":           0.85,
+	"butter":         0.91,
+	"mentega":        0.91,
+	"margarin":       0.91,
+	"milk":           1.03,
+	"susu":           1.03,
+	"santan":         0.97,
+	"coconut milk":   0.97,
+	"water":          1.0,
+	"air":            1.0,
+	"oil":            0.92,
+	"minyak":         0.92,
+	"rice":           0.85,
+	"beras":          0.85,
+	"honey":          1.42,
+	"madu":           1.42,
+	"salt":           1.2,
+	"garam":          1.2,
+	"cocoa":          0.42,
+	"cokelat bubuk":  0.42,
+	"oats":           0.34,
+	"oat":            0.34,
+}
+
+// densityKeys is sorted longest first so "gula halus" wins over "gula".
+var densityKeys = func() []string {
+	keys := make([]string, 0, len(densities))
+	for k := range densities {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return keys
+}()
+
+var wordSplitter = regexp.MustCompile(`[^\p{L}]+`)
+
+// IsValidUnitSystem reports whether system is one of the supported
+// measurement systems.
+func IsValidUnitSystem(system string) bool {
+	return system == UnitSystemMetric || system == UnitSystemUS
+}
+
+// DensityFor looks up the density (g/ml) of an ingredient by name.
+func DensityFor(ingredient string) (float64, bool) {
+	name := " " + strings.Join(wordSplitter.Split(strings.ToLower(ingredient), -1), " ") + " "
+	for _, key := range densityKeys {
+		if strings.Contains(name, " "+key+" ") {
+			return densities[key], true
+		}
+	}
+	return 0, false
+}
+
+// ConvertUnit converts a quantity between two units of the same kind.
+func ConvertUnit(qty float64, from, to string) (float64, error) {
+	src, ok := units[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	dst, ok := units[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if src.Kind != dst.Kind {
+		return 0, errors.New("cannot convert " + src.Kind + " to " + dst.Kind + " without a density")
+	}
+	return qty * src.Factor / dst.Factor, nil
+}
+
+// ConvertToSystem re-expresses an ingredient quantity in the given system.
+// Volumes of ingredients with a known density become grams in metric, and
+// masses of such ingredients become cups or spoons in US customary.
+func ConvertToSystem(qty float64, unit, ingredient, system string) (float64, string) {
+	def, ok := units[unit]
+	if !ok || !IsValidUnitSystem(system) {
+		return qty, unit
+	}
+	if from, known := unitSystems[unit]; !known || from == system {
+		return qty, unit
+	}
+
+	base := qty * def.Factor
+	density, hasDensity := DensityFor(ingredient)
+
+	switch system {
+	case UnitSystemMetric:
+		if def.Kind == KindVolume && hasDensity {
+			return PromoteUnit(base*density, "g")
+		}
+		if def.Kind == KindVolume {
+			return PromoteUnit(base, "ml")
+		}
+		return PromoteUnit(base, "g")
+	default:
+		if def.Kind == KindMass && hasDensity {
+			return PromoteUnit(base/density/units["tsp"].Factor, "tsp")
+		}
+		if def.Kind == KindMass {
+			return PromoteUnit(base/units["oz"].Factor, "oz")
+		}
+		return PromoteUnit(base/units["tsp"].Factor, "tsp")
+	}
+}
+
+// LocalizeAmount converts an ingredient quantity to the given measurement
+// system and returns it with the rebuilt amount text. A quantity that needs
+// no conversion is returned with its amount unchanged.
+func LocalizeAmount(amount string, qty float64, unit, ingredient, system string) (float64, string, string) {
+	converted, to := ConvertToSystem(qty, unit, ingredient, system)
+	if to == unit {
+		return qty, unit, amount
+	}
+	converted = RoundQuantity(converted, to)
+	return converted, to, RebuildAmount(amount, converted, to)
+}
+
+// ConvertTemperature converts between Celsius ("C") and Fahrenheit ("F").
+func ConvertTemperature(value float64, from, to string) float64 {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	switch {
+	case from == "C" && to == "F":
+		return value*9/5 + 32
+	case from == "F" && to == "C":
+		return (value - 32) * 5 / 9
+	default:
+		return value
+	}
+}
+
+var temperaturePattern = regexp.MustCompile(`(?i)(\d{2,3})\s*(?:(?:°|º|derajat)(?:\s*(celsius|celcius|fahrenheit|c|f)\b)?|(celsius|celcius|fahrenheit)\b)`)
+
+// ConvertTemperaturesInText rewrites oven temperatures such as "180°C" or
+// "350 derajat F" found in free text into the given system, rounded to the
+// nearest 5 degrees. A bare "derajat" or "°" is read as Celsius.
+func ConvertTemperaturesInText(text, system string) string {
+	if !IsValidUnitSystem(system) {
+		return text
+	}
+	target := "C"
+	if system == UnitSystemUS {
+		target = "F"
+	}
+
+	return temperaturePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := temperaturePattern.FindStringSubmatch(match)
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return match
+		}
+
+		scale := strings.ToLower(parts[2] + parts[3])
+		from := "C"
+		if strings.HasPrefix(scale, "f") {
+			from = "F"
+		}
+
+		converted := value
+		if from != target {
+			converted = math.Round(ConvertTemperature(value, from, target)/5) * 5
+		}
+		return fmt.Sprintf("%.0f°%s", converted, target)
+	})
+}
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// VideoInfo is what ProbeVideo reads from a video.
+type VideoInfo struct {
+	DurationSeconds float64
+	// Width and Height are the displayed size, with rotation applied.
+	Width  int
+	Height int
+}
+
+// ErrNoVideoStream is returned for files without a video stream.
+var ErrNoVideoStream = errors.New("file has no video stream")
+
+func ffprobePath() string {
+	if p := os.Getenv("FFPROBE_PATH"); p != "" {
+		return p
+	}
+	return "ffprobe"
+}
+
+func ffmpegPath() string {
+	if p := os.Getenv("FFMPEG_PATH"); p != "" {
+		return p
+	}
+	return "ffmpeg"
+}
+
+// runTool runs an ffmpeg tool and returns its standard output. Standard
+// error is included in the error when it fails.
+func runTool(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 300 {
+			msg = msg[:300]
+		}
+		return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		Tags      struct {
+			Rotate string `json:"rotate"`
+		} `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// ProbeVideo reads the duration and size of a video file or URL with
+// ffprobe.
+func ProbeVideo(ctx context.Context, input string) (VideoInfo, error) {
+	out, err := runTool(ctx, ffprobePath(), "-v", "error", "-print_format", "json",
+		"-show_format", "-show_streams", input)
+	if err != nil {
+		return VideoInfo{}, err
+	}
+	return parseProbe(out)
+}
+
+func parseProbe(out []byte) (VideoInfo, error) {
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return VideoInfo{}, fmt.Errorf("unreadable ffprobe output: %w", err)
+	}
+
+	var info VideoInfo
+	info.DurationSeconds, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	for _, st := range probe.Streams {
+		if st.CodecType != "video" {
+			continue
+		}
+		info.Width, info.Height = st.Width, st.Height
+		rotation, _ := strconv.ParseFloat(st.Tags.Rotate, 64)
+		for _, sd := range st.SideDataList {
+			if sd.Rotation != 0 {
+				rotation = sd.Rotation
+			}
+		}
+		if int(math.Abs(rotation))%180 == 90 {
+			info.Width, info.Height = info.Height, info.Width
+		}
+		return info, nil
+	}
+	return VideoInfo{}, ErrNoVideoStream
+}
+
+// PosterAt picks the moment of a video used as its poster: a little into
+// it, past fades from black, but never beyond its end.
+func PosterAt(durationSeconds float64) float64 {
+	return math.Min(durationSeconds/10, 3)
+}
+
+// ExtractPoster grabs the frame at the given second of a video file or URL
+// with ffmpeg and returns it as PNG.
+func ExtractPoster(ctx context.Context, input string, at float64) ([]byte, error) {
+	out, err := runTool(ctx, ffmpegPath(), "-v", "error",
+		"-ss", strconv.FormatFloat(at, 'f', 3, 64), "-i", input,
+		"-frames:v", "1", "-f", "image2pipe", "-vcodec", "png", "pipe:1")
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrNoVideoStream
+	}
+	return out, nil
+}